@@ -19,7 +19,7 @@ var ClearCmd = &cobra.Command{
 	Short:   "Delete the locally cached products file.",
 	Long: `Removes the local products cache file from the user's config directory.
 
-This command is useful for clearing the cached list of products and their aliases previously downloaded from the endoflife.date API. The cache file is stored in the config directory under geol/products.json. If the file does not exist, a message is displayed.`,
+This command is useful for clearing the cached list of products and their aliases previously downloaded from the endoflife.date API. The cache file is stored in the config directory under geol/products.json. If the file does not exist, a message is displayed. The full cache created by 'geol cache refresh --full' is removed as well.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		productsPath, err := utilities.GetProductsPath()
 		if err != nil {
@@ -53,5 +53,26 @@ This command is useful for clearing the cached list of products and their aliase
 			os.Exit(1)
 		}
 		log.Info().Str("path", categoriesPath).Msg("Categories file removed.")
+
+		if err := utilities.RemoveFullCache(); err != nil {
+			log.Error().Err(err).Msg("Error deleting full cache")
+			os.Exit(1)
+		}
+		log.Info().Msg("Full cache (releases, categories and tags listings) removed.")
+
+		identifiersPath, err := utilities.GetIdentifiersPath()
+		if err != nil {
+			log.Error().Err(err).Msg("Error retrieving identifiers path")
+			os.Exit(1)
+		}
+		if err := utilities.RemoveFileIfExists(identifiersPath); err != nil {
+			log.Error().Err(err).Msg("Error deleting identifiers file")
+			os.Exit(1)
+		}
+
+		if err := utilities.RemoveCacheValidators(); err != nil {
+			log.Error().Err(err).Msg("Error deleting cache validators")
+			os.Exit(1)
+		}
 	},
 }
@@ -1,7 +1,10 @@
 package local
 
 import (
+	"os"
+
 	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
 	"github.com/spf13/cobra"
 )
 
@@ -12,11 +15,22 @@ var RefreshCmd = &cobra.Command{
 	Short:   "Download the latest list of products and their aliases from the endoflife.date API and save it locally.",
 	Long: `Fetches the current list of products and their aliases from the endoflife.date API, processes the data into a local JSON file under the user's config directory, and ensures the file is updated with the latest information.
 
-This command is useful for keeping the local product list in sync with the upstream source for further use by the application. The resulting file is stored in the config directory under geol/products.json.`,
+This command is useful for keeping the local product list in sync with the upstream source for further use by the application. The resulting file is stored in the config directory under geol/products.json.
+
+Use --full to also store the release data of every product, and the product lists of every category and tag, under geol/releases, geol/categories and geol/tags. Once done, every command can run without network access using the global --offline flag.`,
+	Example: `geol cache refresh
+geol cache refresh --full`,
 	Run: func(cmd *cobra.Command, args []string) {
 		utilities.RefreshAllCaches(cmd)
+		if full, _ := cmd.Flags().GetBool("full"); full {
+			if err := utilities.FetchAndSaveFullCache(cmd); err != nil {
+				log.Error().Err(err).Msg("Error refreshing the full cache")
+				os.Exit(1)
+			}
+		}
 	},
 }
 
 func init() {
+	RefreshCmd.Flags().Bool("full", false, "Also download the release data of every product for offline use")
 }
@@ -2,6 +2,7 @@ package local
 
 import (
 	"os"
+	"strings"
 
 	"github.com/opt-nc/geol/v2/utilities"
 	"github.com/phuslu/log"
@@ -9,6 +10,26 @@ import (
 )
 
 func init() {
+	StatusCmd.Flags().Bool("all", false, "Show the freshness of every payload of the full cache")
+}
+
+// logResourceStatus logs the freshness of a single cached resource.
+func logResourceStatus(status utilities.ResourceStatus) {
+	event := log.Info()
+	if !status.Fresh {
+		event = log.Warn()
+	}
+	freshness := "fresh"
+	if !status.Fresh {
+		freshness = "stale"
+	}
+	event = event.Str("resource", status.Resource).
+		Str("freshness", freshness).
+		Str("checked", status.CheckedAt.Format("2006-01-02 15:04:05"))
+	if !status.ChangedAt.IsZero() {
+		event = event.Str("changed", status.ChangedAt.Format("2006-01-02 15:04:05"))
+	}
+	event.Bool("conditional", status.Conditional).Msg("")
 }
 
 // StatusCmd represents the status command
@@ -18,7 +39,12 @@ var StatusCmd = &cobra.Command{
 	Short:   "Show information about the local products cache file.",
 	Long: `Displays the status of the local products cache file stored in the user's config directory.
 
-This command prints the last update date and the number of products currently cached in geol/products.json. It helps verify if the cache is present and up to date.`,
+This command prints the last update date and the number of products currently cached in geol/products.json. It helps verify if the cache is present and up to date.
+
+The freshness of each cached resource is reported as well: when it was last checked against the API, when its content last changed, and whether it can be refreshed with a conditional request (ETag or Last-Modified). A resource is stale once older than the cache max age (--max-age, 24h by default). Use --all to list every payload of the full cache.`,
+	Example: `geol cache status
+geol cache status --all
+geol --max-age 72h cache status`,
 	Run: func(cmd *cobra.Command, args []string) {
 		productsPath, err := utilities.GetProductsPath()
 		if err == nil {
@@ -63,6 +89,34 @@ This command prints the last update date and the number of products currently ca
 		}
 		log.Info().Int("Number of categories", len(categories)).Msg("")
 
+		releases, err := utilities.CountCachedReleases()
+		if err != nil {
+			log.Error().Err(err).Msg("Error reading the full cache")
+			errorOccurred = true
+		}
+		log.Info().Int("Number of cached product releases", releases).Msg("")
+
+		statuses, err := utilities.GetCacheStatus()
+		if err != nil {
+			log.Error().Err(err).Msg("Error reading the cache freshness")
+			errorOccurred = true
+		}
+		showAll, _ := cmd.Flags().GetBool("all")
+		fresh, stale := 0, 0
+		for _, status := range statuses {
+			if status.Fresh {
+				fresh++
+			} else {
+				stale++
+			}
+			// The products, tags and categories lists are always shown, full cache payloads with --all only
+			if !showAll && strings.Contains(status.Resource, "/") {
+				continue
+			}
+			logResourceStatus(status)
+		}
+		log.Info().Int("Fresh resources", fresh).Int("Stale resources", stale).Str("max age", utilities.CacheMaxAge.String()).Msg("")
+
 		if errorOccurred {
 			os.Exit(1)
 		}
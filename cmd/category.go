@@ -3,8 +3,6 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 
 	"charm.land/lipgloss/v2"
 	"charm.land/lipgloss/v2/tree"
@@ -43,22 +41,10 @@ geol category cloud`,
 			log.Fatal().Msgf("Category '%s' not found in cache", category)
 		}
 
-		url := utilities.APIUrl + "categories/" + category
-		resp, err := http.Get(url)
+		body, err := utilities.FetchCategoryBody(category)
 		if err != nil {
 			log.Fatal().Err(err).Msgf("Error requesting category '%s'", category)
 		}
-		body, err := io.ReadAll(resp.Body)
-		closeErr := resp.Body.Close()
-		if err != nil {
-			log.Fatal().Err(err).Msgf("Error reading response for category '%s'", category)
-		}
-		if closeErr != nil {
-			log.Fatal().Err(closeErr).Msgf("Error closing response body for category '%s'", category)
-		}
-		if resp.StatusCode != 200 {
-			log.Fatal().Msgf("Category '%s' not found on the API", category)
-		}
 
 		var apiResp struct {
 			Result []struct {
@@ -0,0 +1,191 @@
+package check
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opt-nc/geol/v2/cmd/product"
+	"github.com/opt-nc/geol/v2/cmd/templates"
+	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+)
+
+// maxStackDocumentSize is the maximum size of a stack document posted to the API.
+const maxStackDocumentSize = 1 << 20
+
+// cacheCheckInterval is the time between two checks of the age of the cache by the server.
+const cacheCheckInterval = 10 * time.Minute
+
+// apiServer serves the REST API of 'geol serve'.
+type apiServer struct {
+	cmd   *cobra.Command
+	cache *cacheHealth
+}
+
+// cacheHealth is the outcome of the last background refresh of the cache, reported by /health.
+type cacheHealth struct {
+	mu  sync.Mutex
+	err error
+}
+
+// refreshCache refreshes the cache when it is older than its max age. A failed refresh is logged
+// and reported by /health, the API serving the stale cache meanwhile.
+func (a apiServer) refreshCache() {
+	evaluationLock.Lock()
+	err := utilities.RefreshStaleCache(a.cmd)
+	evaluationLock.Unlock()
+	if err != nil {
+		log.Warn().Err(err).Msg("Error refreshing the cache, serving the cached data")
+	}
+	a.cache.mu.Lock()
+	a.cache.err = err
+	a.cache.mu.Unlock()
+}
+
+// apiError is the body of an error response.
+type apiError struct {
+	Message string `json:"message"`
+	// Problems are the validation errors of an invalid stack document
+	Problems []string `json:"problems,omitempty"`
+}
+
+// writeJSON writes value as the JSON body of a response with the status code.
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Warn().Err(err).Msg("Error writing response")
+	}
+}
+
+// handleHealth reports whether the server can read the products of the cache, and whether the
+// last refresh of the cache failed.
+func (a apiServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	products, err := loadProductsFile()
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "unavailable", "version": utilities.Version, "message": err.Error()})
+		return
+	}
+	health := map[string]any{"status": "ok", "version": utilities.Version, "products": len(products.Products), "offline": utilities.Offline}
+	a.cache.mu.Lock()
+	if a.cache.err != nil {
+		health["status"], health["message"] = "stale", "the last refresh of the cache failed, serving the cached data: "+a.cache.err.Error()
+	}
+	a.cache.mu.Unlock()
+	writeJSON(w, http.StatusOK, health)
+}
+
+// handleOpenAPI writes the OpenAPI description of the API.
+func (a apiServer) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = io.WriteString(w, templates.ServeOpenAPI)
+}
+
+// resolveProduct returns the product name of the {product} path value, a product or an alias,
+// writing an error response when it is unknown.
+func (a apiServer) resolveProduct(w http.ResponseWriter, r *http.Request) (string, []string, bool) {
+	products, err := loadProductsFile()
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, apiError{Message: "the products cache is not available: " + err.Error()})
+		return "", nil, false
+	}
+	query := r.PathValue("product")
+	name, found := resolveProductName(products, query)
+	if !found {
+		writeJSON(w, http.StatusNotFound, apiError{Message: "product " + query + " not found"})
+		return "", nil, false
+	}
+	aliases := []string{}
+	for _, alias := range products.Products[name] {
+		if !strings.EqualFold(alias, name) {
+			aliases = append(aliases, alias)
+		}
+	}
+	return name, aliases, true
+}
+
+// handleProduct resolves a product or an alias to its product name and aliases.
+func (a apiServer) handleProduct(w http.ResponseWriter, r *http.Request) {
+	name, aliases, ok := a.resolveProduct(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"query": r.PathValue("product"), "name": name, "aliases": aliases})
+}
+
+// handleCycles writes the release cycles of a product, the latest number ones with the number
+// query parameter.
+func (a apiServer) handleCycles(w http.ResponseWriter, r *http.Request) {
+	number := 0
+	if value := r.URL.Query().Get("number"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, apiError{Message: "number must be zero or a positive integer"})
+			return
+		}
+		number = n
+	}
+	name, _, ok := a.resolveProduct(w, r)
+	if !ok {
+		return
+	}
+	releases, err := product.FetchProductData(name)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, apiError{Message: err.Error()})
+		return
+	}
+	if number > 0 && number < len(releases.Releases) {
+		releases.Releases = releases.Releases[:number]
+	}
+	writeJSON(w, http.StatusOK, releases)
+}
+
+// handleCheck evaluates the stack document of the body, YAML or JSON, and writes the output of
+// 'geol check --json'. The env and date query parameters are the --env and --date flags.
+func (a apiServer) handleCheck(w http.ResponseWriter, r *http.Request) {
+	today := time.Now()
+	if date := r.URL.Query().Get("date"); date != "" {
+		parsed, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiError{Message: "invalid date " + date + ", expected YYYY-MM-DD"})
+			return
+		}
+		today = parsed
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxStackDocumentSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, apiError{Message: "the stack document is larger than 1 MiB"})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, apiError{Message: err.Error()})
+		return
+	}
+	if len(data) == 0 {
+		writeJSON(w, http.StatusBadRequest, apiError{Message: "the body must be a stack document, like .geol.yaml"})
+		return
+	}
+
+	input, problems, err := loadStackDocument("request", data, r.URL.Query().Get("env"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Message: err.Error(), Problems: problems})
+		return
+	}
+
+	evaluationLock.Lock()
+	result, err := evaluateStack(input, today)
+	evaluationLock.Unlock()
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, apiError{Message: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, checkOutput([]stackResult{result}, result.Score))
+}
@@ -3,17 +3,18 @@ package check
 import (
 	"encoding/json"
 	"fmt"
-	"io"
+	"io/fs"
 	"math"
-	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"golang.org/x/term"
-	"gopkg.in/yaml.v3"
 
 	"charm.land/lipgloss/v2"
 	"charm.land/lipgloss/v2/table"
@@ -24,10 +25,17 @@ import (
 
 func init() {
 	CheckCmd.AddCommand(InitCmd)
-	CheckCmd.Flags().StringP("file", "f", ".geol.yaml", "File to check (default .geol.yaml)")
+	CheckCmd.Flags().StringSliceP("file", "f", []string{".geol.yaml"}, "File to check, can be repeated to check several stacks (default .geol.yaml)")
+	CheckCmd.Flags().StringP("recursive", "r", "", "Check every .geol.yaml file found under this directory")
+	CheckCmd.Flags().StringP("env", "e", "", "Environment whose overlay is applied to the stack (see environments in the stack file)")
 	CheckCmd.Flags().BoolP("strict", "s", false, "Exit with error if any product is EOL")
-	CheckCmd.Flags().Bool("json", false, "Output in JSON format")
+	CheckCmd.Flags().Bool("json", false, "Output in JSON format (same as --format json)")
+	CheckCmd.Flags().String("format", "table", "Output format: "+strings.Join(outputFormats, ", "))
 	CheckCmd.Flags().StringP("date", "d", "", "Reference date for EOL calculations (format YYYY-MM-DD, default: today)")
+	CheckCmd.Flags().String("sbom", "", "Check the components of a CycloneDX or SPDX JSON SBOM instead of the stack file")
+	CheckCmd.Flags().IntSlice("alarms", utilities.DefaultAlarmDays, "Reminders of the --format ics events, in days before each date")
+	CheckCmd.Flags().Bool("no-notify", false, "Do not notify the webhooks of the notify: sections")
+	CheckCmd.Flags().Bool("record", false, "Append the results to the local history (see 'geol check history')")
 }
 
 type stackItem struct {
@@ -39,10 +47,18 @@ type stackItem struct {
 	ManualEol            string `yaml:"manual_eol,omitempty"`
 	LtsStrategy          string `yaml:"lts_strategy,omitempty"`   // "any" or "latest"
 	LtsGraceDays         int    `yaml:"lts_grace_days,omitempty"` // grace period (days) before failing when a newer LTS exists; only applies to lts_strategy: "latest"
+	// Weight of the item in the stack score average, 1 when unset
+	Weight float64 `yaml:"weight,omitempty"`
+	// Waiver lets the item break the check until it expires (see applyWaivers)
+	Waiver *stackWaiver `yaml:"waiver,omitempty"`
 }
 type geolConfig struct {
-	AppName string      `yaml:"app_name"`
-	Stack   []stackItem `yaml:"stack"`
+	AppName  string       `yaml:"app_name"`
+	AppID    string       `yaml:"app_id"`
+	Stack    []stackItem  `yaml:"stack"`
+	Policies []policyRule `yaml:"policies,omitempty"`
+	// Notify are the webhooks notified after the check, on top of those of the configuration file
+	Notify []utilities.NotifyTarget `yaml:"notify,omitempty"`
 }
 
 type stackTableRow struct {
@@ -58,44 +74,53 @@ type stackTableRow struct {
 	// (see standardEolScore). Named debt_score (rather than score) so it isn't confused
 	// with the overall stack score exposed at the top level of the JSON output.
 	DebtScore int `json:"debt_score"`
+	// Weight is the weight of DebtScore in the stack score average (see computeStackScore).
+	Weight float64 `json:"weight"`
+	// Waived is true when the item has a waiver that has not expired
+	Waived bool         `json:"waived"`
+	Waiver *stackWaiver `json:"waiver,omitempty"`
+	// IdEol and the LTS flags are not part of the JSON output, they feed the other formats
+	// (see reportStack).
+	IdEol       string `json:"-"`
+	IsLts       bool   `json:"-"`
+	IsLatestLts bool   `json:"-"`
+	// LatestLts is the latest active LTS cycle of the product, empty when it has none
+	LatestLts string `json:"-"`
 }
 
-// riskThresholdDays is the number of days before EOL at which a component is considered an
-// "upcoming risk". This mirrors RISK_THRESHOLD_DAYS in the geol-check-report.qmd notebook.
-const riskThresholdDays = 180
-
-// standardEolScore is geol's default, built-in EOL scoring formula, mirroring the
-// compute_health_score() logic from assets/_templates/notebooks/check/geol-check-report.qmd.
-// It returns a score between 0 (fully past EOL) and 100 (up to date), based on:
+// standardEolScore is geol's EOL scoring formula, mirroring the compute_health_score() logic
+// from assets/_templates/notebooks/check/geol-check-report.qmd. It returns the score of a tier of
+// the model, by default between 0 (fully past EOL) and 100 (up to date):
 //   - 0 when the component is already past its EOL date
-//   - 30/35/45 when the component is nearing EOL (less than riskThresholdDays remaining),
-//     with higher scores awarded to LTS versions (and the highest to the latest LTS version)
+//   - 30/35/45 when the component is nearing EOL (less than the model RiskThresholdDays
+//     remaining), with higher scores awarded to LTS versions (and the highest to the latest LTS version)
 //   - 100 when the component is on the latest available version (no lag)
 //   - 60/75/95 when a newer major version is available ("Major Lag"), with higher scores
 //     awarded to LTS versions (and the highest to the latest LTS version)
 //   - 80 when only a newer minor/patch version is available ("Minor Lag")
-func standardEolScore(eolDate string, referenceDate time.Time, isLatest bool, version, latestVersion string, isLts, isLatestLts bool) int {
+func standardEolScore(model utilities.ScoringModel, eolDate string, referenceDate time.Time, isLatest bool, version, latestVersion string, isLts, isLatestLts bool) int {
+	tiers := model.Tiers
 	if eolDate != "" {
 		if eolT, err := time.Parse("2006-01-02", eolDate); err == nil {
 			daysUntilEol := int(eolT.Sub(referenceDate).Hours() / 24)
 			switch {
 			case daysUntilEol < 0:
-				return 0
-			case daysUntilEol < riskThresholdDays:
+				return tiers.PastEol
+			case daysUntilEol < model.RiskThresholdDays:
 				switch {
 				case isLts && isLatestLts:
-					return 45
+					return tiers.NearingEolLatestLts
 				case isLts:
-					return 35
+					return tiers.NearingEolLts
 				default:
-					return 30
+					return tiers.NearingEol
 				}
 			}
 		}
 	}
 
 	if isLatest || latestVersion == "" || version == latestVersion {
-		return 100
+		return tiers.Latest
 	}
 
 	// Component is behind the latest known version: determine whether the lag is a major
@@ -112,14 +137,22 @@ func standardEolScore(eolDate string, referenceDate time.Time, isLatest bool, ve
 	if isMajorLag {
 		switch {
 		case isLts && isLatestLts:
-			return 95
+			return tiers.MajorLagLatestLts
 		case isLts:
-			return 75
+			return tiers.MajorLagLts
 		default:
-			return 60
+			return tiers.MajorLag
 		}
 	}
-	return 80
+	return tiers.MinorLag
+}
+
+// itemWeight returns the weight of a stack item in the stack score, 1 when unset.
+func itemWeight(item stackItem) float64 {
+	if item.Weight == 0 {
+		return 1
+	}
+	return item.Weight
 }
 
 // isVersionLts reports whether version matches (or is a sub-version of, e.g. "24.04.1" for
@@ -145,23 +178,23 @@ type stackScore struct {
 	Message string `json:"message"`
 }
 
-// computeStackScore returns the average debt score across all scored components, along
-// with a color/message pair summarizing the overall stack health.
-func computeStackScore(rows []stackTableRow) stackScore {
-	if len(rows) == 0 {
-		return stackScore{Value: 100, Color: "green", Message: "Healthy — No software components to evaluate"}
-	}
-
-	total := 0
+// computeStackScore returns the average debt score across all scored components, weighted by
+// their weight, along with a color/message pair summarizing the overall stack health.
+func computeStackScore(rows []stackTableRow, bands utilities.ScoringBands) stackScore {
+	total, weights := 0.0, 0.0
 	for _, r := range rows {
-		total += r.DebtScore
+		total += float64(r.DebtScore) * r.Weight
+		weights += r.Weight
+	}
+	if weights == 0 {
+		return stackScore{Value: 100, Color: "green", Message: "Healthy — No software components to evaluate"}
 	}
-	avg := int(math.Round(float64(total) / float64(len(rows))))
+	avg := int(math.Round(total / weights))
 
 	switch {
-	case avg >= 80:
+	case avg >= bands.Healthy:
 		return stackScore{Value: avg, Color: "green", Message: "Healthy — All software components are up to date"}
-	case avg >= 50:
+	case avg >= bands.NeedsAttention:
 		return stackScore{Value: avg, Color: "orange", Message: "Needs Attention — Some software components are not up to date"}
 	default:
 		return stackScore{Value: avg, Color: "red", Message: "Critical — Several software components are past end-of-life or severely outdated"}
@@ -169,32 +202,148 @@ func computeStackScore(rows []stackTableRow) stackScore {
 }
 
 // renderScoreValue colorizes a per-component debt score for terminal/markdown table display.
-func renderScoreValue(value int) string {
+func renderScoreValue(value int, bands utilities.ScoringBands) string {
 	switch {
-	case value >= 80:
+	case value >= bands.Healthy:
 		return lipgloss.NewStyle().Foreground(lipgloss.Color("46")).Render(fmt.Sprintf("%d", value))
-	case value >= 50:
+	case value >= bands.NeedsAttention:
 		return lipgloss.NewStyle().Foreground(lipgloss.Color("208")).Render(fmt.Sprintf("%d", value))
 	default:
 		return lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(fmt.Sprintf("%d", value))
 	}
 }
 
-// renderStackScore renders a one-line summary of the overall stack debt score.
-func renderStackScore(score stackScore) string {
-	colorCode := map[string]string{"green": "46", "orange": "208", "red": "196"}[score.Color]
+// scoreColorCode returns the terminal color of a stack score color.
+func scoreColorCode(color string) string {
+	colorCode := map[string]string{"green": "46", "orange": "208", "red": "196"}[color]
 	if colorCode == "" {
 		colorCode = "252"
 	}
-	valueStr := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(colorCode)).Render(fmt.Sprintf("%d/100", score.Value))
+	return colorCode
+}
+
+// renderStackScore renders a one-line summary of the overall stack debt score.
+func renderStackScore(score stackScore) string {
+	valueStr := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(scoreColorCode(score.Color))).Render(fmt.Sprintf("%d/100", score.Value))
 	return fmt.Sprintf("Stack Debt Score: %s — %s", valueStr, score.Message)
 }
 
-// getStackTableRows returns a slice of StackTableRow for a given stack and today date
-func getStackTableRows(stack []stackItem, today time.Time) ([]stackTableRow, bool, []string) {
+// renderScoreBand colorizes a stack score value with the color it was computed with, i.e. with the
+// bands of the scoring model of its stack.
+func renderScoreBand(score stackScore) string {
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(scoreColorCode(score.Color))).Render(fmt.Sprintf("%d", score.Value))
+}
+
+// productBodies memoizes the products/{name} payloads fetched during a check run, so that each
+// product is requested once even when several lookups or stack items reference it.
+var productBodies = struct {
+	sync.Mutex
+	data map[string][]byte
+}{data: map[string][]byte{}}
+
+// fetchProductBody returns the memoized products/{prod} payload, fetching it on first use.
+func fetchProductBody(prod string) ([]byte, error) {
+	productBodies.Lock()
+	body, ok := productBodies.data[prod]
+	productBodies.Unlock()
+	if ok {
+		return body, nil
+	}
+	body, err := utilities.FetchProductBody(prod)
+	if err != nil {
+		return nil, err
+	}
+	productBodies.Lock()
+	productBodies.data[prod] = body
+	productBodies.Unlock()
+	return body, nil
+}
+
+// resolveProductName returns the endoflife.date product name matching idEol, either directly
+// or through one of its aliases (case-insensitive).
+func resolveProductName(products utilities.ProductsFile, idEol string) (string, bool) {
+	for name, aliases := range products.Products {
+		if strings.EqualFold(idEol, name) {
+			return name, true
+		}
+		for _, alias := range aliases {
+			if strings.EqualFold(idEol, alias) {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// prefetchProductBodies resets the payload memo and fetches, in parallel, the payload of every
+// product referenced by the stack. Failures are not memoized: the lookups will report them.
+func prefetchProductBodies(stack []stackItem) {
+	productBodies.Lock()
+	productBodies.data = map[string][]byte{}
+	productBodies.Unlock()
+
+	productsPath, err := utilities.GetProductsPath()
+	if err != nil {
+		return
+	}
+	products, err := utilities.GetProductsWithCacheRefresh(nil, productsPath)
+	if err != nil {
+		return
+	}
+	seen := map[string]bool{}
+	var names []string
+	for _, item := range stack {
+		if item.Skip {
+			continue
+		}
+		if name, found := resolveProductName(products, item.IdEol); found && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	utilities.RunConcurrently(len(names), func(i int) {
+		if _, err := fetchProductBody(names[i]); err != nil {
+			log.Debug().Err(err).Msgf("Error prefetching %s", names[i])
+		}
+	})
+}
+
+// Kinds of stack violations, used as stable rule ids by the SARIF output.
+const (
+	violationPastEol          = "past-eol"
+	violationNearingEol       = "nearing-eol"
+	violationNotLatest        = "not-latest"
+	violationLtsPolicy        = "lts-policy"
+	violationInvalidManualEol = "invalid-manual-eol"
+)
+
+// stackViolation is a problem found on a stack item, reported after the stack table.
+type stackViolation struct {
+	Kind string
+	// Rule is the id of the broken policy, for violations of kind policy
+	Rule string
+	// Item is the name of the stack item
+	Item string
+	// Warning is true for violations that do not fail the check (e.g. nearing EOL)
+	Warning bool
+	// Waived is true for violations turned into warnings by a waiver
+	Waived  bool
+	Message string
+}
+
+// failsStrict returns true when the violation fails the check in strict mode: not-latest
+// violations are only reported.
+func (v stackViolation) failsStrict() bool {
+	return !v.Warning && v.Kind != violationNotLatest
+}
+
+// getStackTableRows returns a slice of StackTableRow for a given stack and today date. It returns an
+// error when an item cannot be evaluated, e.g. an unknown version.
+func getStackTableRows(stack []stackItem, today time.Time, model utilities.ScoringModel) ([]stackTableRow, []stackViolation, error) {
+	prefetchProductBodies(stack)
+
 	rows := []stackTableRow{}
-	errorOut := false
-	violations := []string{}
+	violations := []stackViolation{}
 
 	for _, item := range stack {
 		// Skip items marked with skip: true
@@ -210,24 +359,7 @@ func getStackTableRows(stack []stackItem, today time.Time) ([]stackTableRow, boo
 			if err == nil {
 				products, err := utilities.GetProductsWithCacheRefresh(nil, productsPath)
 				if err == nil {
-					prod := item.IdEol
-					found := false
-					for name, aliases := range products.Products {
-						if strings.EqualFold(prod, name) {
-							found = true
-							break
-						}
-						for _, alias := range aliases {
-							if strings.EqualFold(prod, alias) {
-								found = true
-								break
-							}
-						}
-						if found {
-							break
-						}
-					}
-					if found {
+					if _, found := resolveProductName(products, item.IdEol); found {
 						log.Warn().Msgf("Product %s is available in eol.date API but has manual_eol set. Consider removing manual_eol to use official EOL data", item.Name)
 					}
 				}
@@ -244,29 +376,29 @@ func getStackTableRows(stack []stackItem, today time.Time) ([]stackTableRow, boo
 			var daysInt int
 			eolT, parseErr := time.Parse("2006-01-02", eolDate)
 			if parseErr != nil {
-				log.Error().Msgf("Invalid manual_eol date format for %s %s: %s (expected YYYY-MM-DD)", item.Name, item.Version, item.ManualEol)
-				violations = append(violations, fmt.Sprintf("%s %s has invalid manual_eol date format: %s (expected YYYY-MM-DD)", item.Name, item.Version, item.ManualEol))
-				errorOut = true
+				violations = append(violations, stackViolation{
+					Kind: violationInvalidManualEol, Item: item.Name,
+					Message: fmt.Sprintf("%s %s has invalid manual_eol date format: %s (expected YYYY-MM-DD)", item.Name, item.Version, item.ManualEol),
+				})
 				continue
 			}
 			daysInt = int(eolT.Sub(today).Hours() / 24)
 			daysStr = fmt.Sprintf("%d", daysInt)
 			if daysInt < 0 {
 				status = "EOL"
-				errorOut = true
 				years := -daysInt / 365
 				months := (-daysInt % 365) / 30
 				days := (-daysInt % 365) % 30
-				log.Error().Msgf(
-					"%s %s (%s) is %dy %dm %dd past EOL (manual EOL: %s)",
-					item.Name, item.Version, item.Name, years, months, days, eolDate,
-				)
+				violations = append(violations, stackViolation{
+					Kind: violationPastEol, Item: item.Name,
+					Message: fmt.Sprintf("%s %s (%s) is %dy %dm %dd past EOL (manual EOL: %s)", item.Name, item.Version, item.Name, years, months, days, eolDate),
+				})
 			} else if daysInt < 30 {
 				status = "WARN"
-				log.Warn().Msgf(
-					"%s %s (%s) is nearing EOL in %dd (manual EOL: %s)",
-					item.Name, item.Version, item.Name, daysInt, eolDate,
-				)
+				violations = append(violations, stackViolation{
+					Kind: violationNearingEol, Item: item.Name, Warning: true,
+					Message: fmt.Sprintf("%s %s (%s) is nearing EOL in %dd (manual EOL: %s)", item.Name, item.Version, item.Name, daysInt, eolDate),
+				})
 			} else {
 				status = "OK"
 			}
@@ -278,7 +410,11 @@ func getStackTableRows(stack []stackItem, today time.Time) ([]stackTableRow, boo
 				Days:          daysStr,
 				IsLatest:      false,
 				LatestVersion: "-",
-				DebtScore:     standardEolScore(eolDate, today, false, item.Version, "", manualIsLts, manualIsLatestLts),
+				DebtScore:     standardEolScore(model, eolDate, today, false, item.Version, "", manualIsLts, manualIsLatestLts),
+				Weight:        itemWeight(item),
+				IdEol:         item.IdEol,
+				IsLts:         manualIsLts,
+				IsLatestLts:   manualIsLatestLts,
 			})
 			continue
 		}
@@ -289,11 +425,11 @@ func getStackTableRows(stack []stackItem, today time.Time) ([]stackTableRow, boo
 		if item.LtsStrategy != "" {
 			activeLts, latestLts, latestLtsDate, ltsErr := lookupLtsInfo(item.IdEol)
 			if ltsErr != nil {
-				log.Fatal().Msgf("LTS strategy check failed for %s: %v", item.Name, ltsErr)
+				return nil, nil, fmt.Errorf("LTS strategy check failed for %s: %w", item.Name, ltsErr)
 			}
 			lookedUpActiveLts, lookedUpLatestLts = activeLts, latestLts
 			if len(activeLts) == 0 {
-				log.Fatal().Msgf("%s (%s): lts_strategy is set to '%s' but no active LTS versions are available for this product", item.Name, item.IdEol, item.LtsStrategy)
+				return nil, nil, fmt.Errorf("%s (%s): lts_strategy is set to '%s' but no active LTS versions are available for this product", item.Name, item.IdEol, item.LtsStrategy)
 			}
 
 			switch item.LtsStrategy {
@@ -306,7 +442,10 @@ func getStackTableRows(stack []stackItem, today time.Time) ([]stackTableRow, boo
 					}
 				}
 				if !isLts {
-					log.Fatal().Msgf("%s %s: lts_strategy 'any' requires an active LTS version, but %s is not LTS (active LTS: %s)", item.Name, item.Version, item.Version, strings.Join(activeLts, ", "))
+					violations = append(violations, stackViolation{
+						Kind: violationLtsPolicy, Item: item.Name,
+						Message: fmt.Sprintf("%s %s: lts_strategy 'any' requires an active LTS version, but %s is not LTS (active LTS: %s)", item.Name, item.Version, item.Version, strings.Join(activeLts, ", ")),
+					})
 				}
 			case "latest":
 				if item.Version != latestLts {
@@ -318,17 +457,18 @@ func getStackTableRows(stack []stackItem, today time.Time) ([]stackTableRow, boo
 							daysSinceLatestLts := int(today.Sub(ltsRelDate).Hours() / 24)
 							if daysSinceLatestLts < item.LtsGraceDays {
 								withinGrace = true
-								log.Warn().Msgf(
-									"%s %s: lts_strategy 'latest' — newer LTS %s was released %dd ago (grace period: %dd). Update before grace period expires.",
-									item.Name, item.Version, latestLts, daysSinceLatestLts, item.LtsGraceDays,
-								)
+								violations = append(violations, stackViolation{
+									Kind: violationLtsPolicy, Item: item.Name, Warning: true,
+									Message: fmt.Sprintf("%s %s: lts_strategy 'latest' — newer LTS %s was released %dd ago (grace period: %dd). Update before grace period expires.", item.Name, item.Version, latestLts, daysSinceLatestLts, item.LtsGraceDays),
+								})
 							}
 						}
 					}
 					if !withinGrace {
-						log.Error().Msgf("%s %s: lts_strategy 'latest' requires the latest LTS version (%s), but got %s", item.Name, item.Version, latestLts, item.Version)
-						violations = append(violations, fmt.Sprintf("%s %s is not the latest LTS version (lts_strategy: latest, latest LTS: %s)", item.Name, item.Version, latestLts))
-						errorOut = true
+						violations = append(violations, stackViolation{
+							Kind: violationLtsPolicy, Item: item.Name,
+							Message: fmt.Sprintf("%s %s is not the latest LTS version (lts_strategy: latest, latest LTS: %s)", item.Name, item.Version, latestLts),
+						})
 					}
 				}
 			}
@@ -336,7 +476,7 @@ func getStackTableRows(stack []stackItem, today time.Time) ([]stackTableRow, boo
 
 		eolDate, isLatest, latestVersion, eolLookupErr := lookupEolDate(item.IdEol, item.Version, today)
 		if eolLookupErr != nil {
-			log.Fatal().Msgf("%s %s: %v", item.Name, item.Version, eolLookupErr)
+			return nil, nil, fmt.Errorf("%s %s: %w", item.Name, item.Version, eolLookupErr)
 		}
 
 		// Determine LTS status for score computation, reusing the lookup already performed
@@ -358,21 +498,20 @@ func getStackTableRows(stack []stackItem, today time.Time) ([]stackTableRow, boo
 			daysStr = fmt.Sprintf("%d", daysInt)
 			if daysInt < 0 {
 				status = "EOL"
-				errorOut = true
 				// Calculate the time elapsed since EOL
 				years := -daysInt / 365
 				months := (-daysInt % 365) / 30
 				days := (-daysInt % 365) % 30
-				log.Error().Msgf(
-					"%s %s (%s) is %dy %dm %dd past EOL (EOL: %s)",
-					item.Name, item.Version, item.Name, years, months, days, eolDate,
-				)
+				violations = append(violations, stackViolation{
+					Kind: violationPastEol, Item: item.Name,
+					Message: fmt.Sprintf("%s %s (%s) is %dy %dm %dd past EOL (EOL: %s)", item.Name, item.Version, item.Name, years, months, days, eolDate),
+				})
 			} else if daysInt < 30 {
 				status = "WARN"
-				log.Warn().Msgf(
-					"%s %s (%s) is nearing EOL in %dd (EOL: %s)",
-					item.Name, item.Version, item.Name, daysInt, eolDate,
-				)
+				violations = append(violations, stackViolation{
+					Kind: violationNearingEol, Item: item.Name, Warning: true,
+					Message: fmt.Sprintf("%s %s (%s) is nearing EOL in %dd (EOL: %s)", item.Name, item.Version, item.Name, daysInt, eolDate),
+				})
 			} else {
 				status = "OK"
 			}
@@ -389,13 +528,20 @@ func getStackTableRows(stack []stackItem, today time.Time) ([]stackTableRow, boo
 			IsLatest:      isLatest,
 			LatestVersion: latestVersion,
 			LtsStrategy:   item.LtsStrategy,
-			DebtScore:     standardEolScore(eolDate, today, isLatest, item.Version, latestVersion, isLts, isLatestLts),
+			DebtScore:     standardEolScore(model, eolDate, today, isLatest, item.Version, latestVersion, isLts, isLatestLts),
+			Weight:        itemWeight(item),
+			IdEol:         item.IdEol,
+			IsLts:         isLts,
+			IsLatestLts:   isLatestLts,
+			LatestLts:     latestLtsForScore,
 		})
 
 		// Check always-latest flag
 		if item.ShouldAlwaysBeLatest && !isLatest {
-			violations = append(violations, fmt.Sprintf("%s %s is not the latest version (latest: %s)", item.Name, item.Version, latestVersion))
-			violations = append(violations, fmt.Sprintf("%s should be in the latest version (current: %s, latest: %s)", item.Name, item.Version, latestVersion))
+			violations = append(violations, stackViolation{
+				Kind: violationNotLatest, Item: item.Name,
+				Message: fmt.Sprintf("%s should be in the latest version (current: %s, latest: %s)", item.Name, item.Version, latestVersion),
+			})
 		}
 	}
 	// Sort rows by Status: EOL, WARN, OK, INFO, then by Days (from smallest to largest)
@@ -432,7 +578,7 @@ func getStackTableRows(stack []stackItem, today time.Time) ([]stackTableRow, boo
 		// fallback to lexicographical if problem
 		return rows[i].Days < rows[j].Days
 	})
-	return rows, errorOut, violations
+	return rows, violations, nil
 }
 
 // findVersionSuggestion fetches all releases for a product and uses semver to suggest
@@ -444,15 +590,10 @@ func findVersionSuggestion(prod, version string) string {
 		return ""
 	}
 
-	url := utilities.APIUrl + "products/" + prod
-	resp, err := http.Get(url)
+	body, err := fetchProductBody(prod)
 	if err != nil {
 		return ""
 	}
-	body, err := io.ReadAll(resp.Body)
-	if cerr := resp.Body.Close(); cerr != nil || err != nil || resp.StatusCode != 200 {
-		return ""
-	}
 
 	var apiRespProd struct {
 		Result struct {
@@ -490,6 +631,31 @@ func findVersionSuggestion(prod, version string) string {
 	return ""
 }
 
+// hasReleaseCycle returns true when version is the name of a release cycle of prod, the versions
+// that lookupEolDate can evaluate.
+func hasReleaseCycle(prod, version string) bool {
+	body, err := fetchProductBody(prod)
+	if err != nil {
+		return false
+	}
+	var payload struct {
+		Result struct {
+			Releases []struct {
+				Name string `json:"name"`
+			} `json:"releases"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return false
+	}
+	for _, rel := range payload.Result.Releases {
+		if rel.Name == version {
+			return true
+		}
+	}
+	return false
+}
+
 // lookupEolDate returns the EOL date for a given id_eol and version, along with whether the
 // version is the latest cycle available as of referenceDate, and the name of that latest cycle.
 // Cycles released after referenceDate are excluded so that Latest/Is Latest reflect what was
@@ -507,82 +673,22 @@ func lookupEolDate(idEol, version string, referenceDate time.Time) (string, bool
 		return "", false, "", fmt.Errorf("error retrieving products from cache: %w", err)
 	}
 
-	prod := idEol
-
-	found := false
-	for name, aliases := range products.Products {
-		if strings.EqualFold(prod, name) {
-			found = true
-			prod = name
-			break
-		}
-		for _, alias := range aliases {
-			if strings.EqualFold(prod, alias) {
-				found = true
-				prod = name
-				break
-			}
-		}
-		if found {
-			break
-		}
-	}
-
+	prod, found := resolveProductName(products, idEol)
 	if !found {
 		return "", false, "", fmt.Errorf("product with id_eol %s not found in the API", idEol)
 	}
 
 	if len(prod) > 0 {
-		url := utilities.APIUrl + "products/" + prod + "/releases/" + version
-		resp, err := http.Get(url)
+		body, err := fetchProductBody(prod)
 		if err != nil {
 			return "", false, "", fmt.Errorf("error requesting %s: %w", prod, err)
 		}
-		body, err := io.ReadAll(resp.Body)
-		if cerr := resp.Body.Close(); cerr != nil {
-			return "", false, "", fmt.Errorf("error closing HTTP body for %s: %w", prod, cerr)
-		}
-		if err != nil {
-			return "", false, "", fmt.Errorf("error reading response for %s: %w", prod, err)
-		}
-		if resp.StatusCode != 200 {
-			if suggestion := findVersionSuggestion(prod, version); suggestion != "" {
-				log.Info().Msgf("Version %q not found for product %q in endoflife.date. Did you mean %q? Consider updating your .geol.yaml to: version: \"%s\"", version, prod, suggestion, suggestion)
-			}
-			return "", false, "", fmt.Errorf("product %s version %s not found (status %d)", prod, version, resp.StatusCode)
-		}
-		var apiResp struct {
-			Result struct {
-				Name    string `json:"name"`
-				EolFrom string `json:"eolFrom"`
-				IsEol   bool   `json:"isEol"`
-			} `json:"result"`
-		}
-
-		if err := json.Unmarshal(body, &apiResp); err != nil {
-			return "", false, "", fmt.Errorf("error decoding JSON for %s: %w", prod, err)
-		}
-
-		url = utilities.APIUrl + "products/" + prod
-		resp, err = http.Get(url)
-		if err != nil {
-			return "", false, "", fmt.Errorf("error requesting %s: %w", prod, err)
-		}
-		body, err = io.ReadAll(resp.Body)
-		if cerr := resp.Body.Close(); cerr != nil {
-			return "", false, "", fmt.Errorf("error closing HTTP body for %s: %w", prod, cerr)
-		}
-		if err != nil {
-			return "", false, "", fmt.Errorf("error reading response for %s: %w", prod, err)
-		}
-		if resp.StatusCode != 200 {
-			return "", false, "", fmt.Errorf("product %s not found (status %d)", prod, resp.StatusCode)
-		}
 		var apiRespProd struct {
 			Result struct {
 				Releases []struct {
 					Name        string `json:"name"`
 					ReleaseDate string `json:"releaseDate"`
+					EolFrom     string `json:"eolFrom"`
 				} `json:"releases"`
 			} `json:"result"`
 		}
@@ -591,6 +697,21 @@ func lookupEolDate(idEol, version string, referenceDate time.Time) (string, bool
 			return "", false, "", fmt.Errorf("error decoding JSON for %s: %w", prod, err)
 		}
 
+		// Find the release cycle matching the requested version.
+		eolFrom, releaseFound := "", false
+		for _, rel := range apiRespProd.Result.Releases {
+			if rel.Name == version {
+				eolFrom, releaseFound = rel.EolFrom, true
+				break
+			}
+		}
+		if !releaseFound {
+			if suggestion := findVersionSuggestion(prod, version); suggestion != "" {
+				log.Info().Msgf("Version %q not found for product %q in endoflife.date. Did you mean %q? Consider updating your .geol.yaml to: version: \"%s\"", version, prod, suggestion, suggestion)
+			}
+			return "", false, "", fmt.Errorf("product %s version %s not found", prod, version)
+		}
+
 		// Determine latest cycle available as of referenceDate by excluding cycles
 		// whose releaseDate is after the reference date.
 		isLatest := false
@@ -611,7 +732,7 @@ func lookupEolDate(idEol, version string, referenceDate time.Time) (string, bool
 			isLatest = true
 		}
 
-		return apiResp.Result.EolFrom, isLatest, latestVersion, nil
+		return eolFrom, isLatest, latestVersion, nil
 	}
 	return "", false, "", nil
 }
@@ -634,44 +755,15 @@ func lookupLtsInfo(idEol string) ([]string, string, string, error) {
 		return nil, "", "", fmt.Errorf("error retrieving products from cache: %w", err)
 	}
 
-	prod := idEol
-	found := false
-	for name, aliases := range products.Products {
-		if strings.EqualFold(prod, name) {
-			found = true
-			prod = name
-			break
-		}
-		for _, alias := range aliases {
-			if strings.EqualFold(prod, alias) {
-				found = true
-				prod = name
-				break
-			}
-		}
-		if found {
-			break
-		}
-	}
+	prod, found := resolveProductName(products, idEol)
 	if !found {
 		return nil, "", "", fmt.Errorf("product with id_eol %s not found in the API", idEol)
 	}
 
-	url := utilities.APIUrl + "products/" + prod
-	resp, err := http.Get(url)
+	body, err := fetchProductBody(prod)
 	if err != nil {
 		return nil, "", "", fmt.Errorf("error requesting %s: %w", prod, err)
 	}
-	body, err := io.ReadAll(resp.Body)
-	if cerr := resp.Body.Close(); cerr != nil {
-		return nil, "", "", fmt.Errorf("error closing HTTP body for %s: %w", prod, cerr)
-	}
-	if err != nil {
-		return nil, "", "", fmt.Errorf("error reading response for %s: %w", prod, err)
-	}
-	if resp.StatusCode != 200 {
-		return nil, "", "", fmt.Errorf("product %s not found (status %d)", prod, resp.StatusCode)
-	}
 
 	var apiRespProd struct {
 		Result struct {
@@ -706,15 +798,12 @@ func lookupLtsInfo(idEol string) ([]string, string, string, error) {
 }
 
 // renderStackTable renders the stack table using lipgloss/table
-func renderStackTable(rows []stackTableRow) string {
+func renderStackTable(rows []stackTableRow, bands utilities.ScoringBands) string {
 	green := lipgloss.NewStyle().Foreground(lipgloss.Color("46"))
 	orange := lipgloss.NewStyle().Foreground(lipgloss.Color("208"))
 	red := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
 
-	t := table.New()
-	t.Headers(
-		"Software", "Version", "EOL Date", "Status", "Days", "Is Latest", "Latest", "Debt Score",
-	)
+	t := newStackTable("Software", "Version", "EOL Date", "Status", "Days", "Is Latest", "Latest", "Debt Score")
 	for _, r := range rows {
 		var daysStr string
 		var statusStr string
@@ -733,6 +822,9 @@ func renderStackTable(rows []stackTableRow) string {
 			statusStr = r.Status
 			daysStr = r.Days
 		}
+		if r.Waived {
+			statusStr = orange.Render(r.Status + " (waived)")
+		}
 		if r.IsLatest {
 			latestStr = green.Render("true")
 		} else {
@@ -746,9 +838,16 @@ func renderStackTable(rows []stackTableRow) string {
 			daysStr,
 			latestStr,
 			r.LatestVersion,
-			renderScoreValue(r.DebtScore),
+			renderScoreValue(r.DebtScore, bands),
 		)
 	}
+	return t.Render()
+}
+
+// newStackTable returns a table with the headers and the style of the stack table, bordered in a
+// terminal and in markdown otherwise.
+func newStackTable(headers ...string) *table.Table {
+	t := table.New().Headers(headers...)
 	if term.IsTerminal(int(os.Stdout.Fd())) {
 		t.Border(lipgloss.RoundedBorder())
 	} else {
@@ -760,10 +859,9 @@ func renderStackTable(rows []stackTableRow) string {
 	t.BorderRight(false)
 	t.BorderStyle(lipgloss.NewStyle().BorderForeground(lipgloss.Color("63")))
 	t.StyleFunc(func(row, col int) lipgloss.Style {
-		padding := 1
-		return lipgloss.NewStyle().Foreground(lipgloss.Color("252")).Align(lipgloss.Left).Padding(0, padding)
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("252")).Align(lipgloss.Left).Padding(0, 1)
 	})
-	return t.Render()
+	return t
 }
 
 // validationResult holds validation errors categorized by type
@@ -773,6 +871,19 @@ type validationResult struct {
 	constraint []string
 }
 
+// problems returns the validation errors as messages.
+func (result validationResult) problems() []string {
+	var problems []string
+	for _, missing := range result.missing {
+		problems = append(problems, "Missing or empty key: "+missing)
+	}
+	problems = append(problems, result.duplicates...)
+	for _, constraint := range result.constraint {
+		problems = append(problems, "Constraint error: "+constraint)
+	}
+	return problems
+}
+
 // checkRequiredKeys validates required keys in geolConfig and returns categorized errors
 func checkRequiredKeys(config geolConfig) validationResult {
 	result := validationResult{
@@ -816,10 +927,20 @@ func checkRequiredKeys(config geolConfig) validationResult {
 		if item.LtsGraceDays < 0 {
 			result.missing = append(result.missing, fmt.Sprintf("stack[%d].lts_grace_days must be >= 0, got %d", i, item.LtsGraceDays))
 		}
+		result.constraint = append(result.constraint, checkWaiver(i, item.Waiver)...)
+		if item.Weight < 0 {
+			result.constraint = append(result.constraint, fmt.Sprintf("stack[%d].weight must be positive, got %v", i, item.Weight))
+		}
 		if item.ShouldAlwaysBeLatest && item.LtsStrategy != "" {
 			result.constraint = append(result.constraint, fmt.Sprintf("stack[%d] cannot define both always-latest and lts_strategy for the same product", i))
 		}
 	}
+	result.constraint = append(result.constraint, checkPolicies(config.Policies)...)
+	for i, target := range config.Notify {
+		if err := target.Validate(); err != nil {
+			result.constraint = append(result.constraint, fmt.Sprintf("notify[%d]: %v", i, err))
+		}
+	}
 	return result
 }
 
@@ -832,105 +953,421 @@ var CheckCmd = &cobra.Command{
 Try using 'geol check init' to generate a sample stack YAML file. See https://opt-nc.github.io/geol/docs/tutorial-basics/check-command for more`,
 	Example: `geol check
 geol check --file stack.yaml
-geol check --json`,
+geol check --file api/.geol.yaml --file web/.geol.yaml
+geol check --recursive . --strict
+geol check --env prod
+geol check --json
+geol check --format cyclonedx > stack.cdx.json
+geol check --format sarif > geol.sarif
+geol check --format junit > geol-junit.xml
+geol check --format ics --alarms 90,30 > eol.ics
+geol check --sbom bom.cdx.json
+geol check --record`,
 	Run: func(cmd *cobra.Command, args []string) {
-		file, _ := cmd.Flags().GetString("file")
-		strict, _ := cmd.Flags().GetBool("strict")
-		jsonOutput, _ := cmd.Flags().GetBool("json")
-		_, err := os.Stat(file)
-		if err != nil {
-			log.Fatal().Msg("Error: the file does not exist: " + file)
+		if sbomPath, _ := cmd.Flags().GetString("sbom"); sbomPath != "" {
+			checkSbom(cmd, sbomPath)
+			return
 		}
+		files, _ := cmd.Flags().GetStringSlice("file")
+		env, _ := cmd.Flags().GetString("env")
+		if dir, _ := cmd.Flags().GetString("recursive"); dir != "" {
+			found, err := findStackFiles(dir)
+			if err != nil {
+				log.Fatal().Err(err).Msgf("Error searching stack files in %s", dir)
+			}
+			if len(found) == 0 {
+				log.Fatal().Msgf("No .geol.yaml file found in %s", dir)
+			}
+			files = found
+		}
+		inputs := make([]stackInput, 0, len(files))
+		for _, file := range files {
+			inputs = append(inputs, readStackFile(file, env))
+		}
+		reportStacks(cmd, inputs)
+	},
+}
 
-		// Read the YAML file
-		data, err := os.ReadFile(file)
+// findStackFiles returns the .geol.yaml files found under dir, sorted by path.
+func findStackFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			log.Fatal().Msg("Error reading file: " + err.Error())
+			return err
 		}
-
-		var config geolConfig
-		if err := yaml.Unmarshal(data, &config); err != nil {
-			log.Fatal().Msg("YAML format error: " + err.Error())
+		if d.IsDir() {
+			if path != dir && skippedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == ".geol.yaml" {
+			files = append(files, path)
 		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
 
-		validation := checkRequiredKeys(config)
-		hasErrors := false
+// readStackFile reads and validates a stack file, resolving its extends chain and the overlays
+// of the env environment when env is not empty. It exits with an error when it is invalid.
+func readStackFile(file, env string) stackInput {
+	input, err := loadStackFile(file, env)
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+	return input
+}
 
-		// Log missing fields
-		if len(validation.missing) > 0 {
-			for _, missing := range validation.missing {
-				log.Error().Msgf("Missing or empty key: %s", missing)
-			}
-			hasErrors = true
-		}
+// loadStackFile reads and validates a stack file like readStackFile, but returns an error when it
+// is invalid. Validation errors are logged one by one.
+func loadStackFile(file, env string) (stackInput, error) {
+	_, err := os.Stat(file)
+	if err != nil {
+		return stackInput{}, fmt.Errorf("Error: the file does not exist: %s", file)
+	}
 
-		// Log duplicate names
-		if len(validation.duplicates) > 0 {
-			for _, duplicate := range validation.duplicates {
-				log.Error().Msg(duplicate)
-			}
-			hasErrors = true
+	resolved, err := resolveStackFile(file, env)
+	if err != nil {
+		return stackInput{}, fmt.Errorf("Error reading the stack: %w", err)
+	}
+	config := resolved.geolConfig
+
+	problems := checkRequiredKeys(config).problems()
+	for _, problem := range problems {
+		log.Error().Msg(problem)
+	}
+	if len(problems) > 0 {
+		return stackInput{}, fmt.Errorf("Validation failed for %s: please fix the errors above", file)
+	}
+
+	title := config.AppName
+	if resolved.Layered {
+		if err := validateResolvedStack(file, resolved); err != nil {
+			return stackInput{}, fmt.Errorf("The merged stack of %s does not match geol_stack.cue: %w", file, err)
 		}
+	}
+	if env != "" {
+		title += " [" + env + "]"
+		log.Info().Msgf("Using environment %s of %s", env, file)
+	}
 
-		// Log constraint errors
-		if len(validation.constraint) > 0 {
-			for _, constraint := range validation.constraint {
-				log.Error().Msgf("Constraint error: %s", constraint)
-			}
-			hasErrors = true
+	return stackInput{
+		Title:     title,
+		AppID:     config.AppID,
+		File:      file,
+		Locations: resolved.Locations,
+		Stack:     config.Stack,
+		Policies:  config.Policies,
+		Scoring:   resolved.Scoring,
+		Notify:    config.Notify,
+	}, nil
+}
+
+// loadStackDocument reads and validates a stack document that is not read from a file, such as a
+// stack posted to 'geol serve'. It returns the validation problems of an invalid stack, if any.
+func loadStackDocument(name string, data []byte, env string) (stackInput, []string, error) {
+	resolved, err := resolveStackDocument(name, data, env)
+	if err != nil {
+		return stackInput{}, nil, err
+	}
+	config := resolved.geolConfig
+	if problems := checkRequiredKeys(config).problems(); len(problems) > 0 {
+		return stackInput{}, problems, fmt.Errorf("validation failed for %s", name)
+	}
+	if err := validateResolvedStack(name, resolved); err != nil {
+		return stackInput{}, nil, err
+	}
+	title := config.AppName
+	if env != "" {
+		title += " [" + env + "]"
+	}
+	return stackInput{
+		Title:    title,
+		AppID:    config.AppID,
+		Stack:    config.Stack,
+		Policies: config.Policies,
+		Scoring:  resolved.Scoring,
+	}, nil, nil
+}
+
+// outputFormats are the values accepted by the --format flag of the check commands.
+var outputFormats = []string{"table", "json", "cyclonedx", "sarif", "junit", "ics"}
+
+// outputFormat returns the --format flag value, "json" when --json is set. It exits with an
+// error on an unknown format.
+func outputFormat(cmd *cobra.Command) string {
+	format, _ := cmd.Flags().GetString("format")
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		format = "json"
+	}
+	if format == "" {
+		format = "table"
+	}
+	if !slices.Contains(outputFormats, format) {
+		log.Fatal().Msgf("Unknown --format %q (expected one of: %s)", format, strings.Join(outputFormats, ", "))
+	}
+	return format
+}
+
+// referenceDate returns the --date flag value, today when it is not set.
+func referenceDate(cmd *cobra.Command) time.Time {
+	dateStr, _ := cmd.Flags().GetString("date")
+	if dateStr == "" {
+		return time.Now()
+	}
+	parsed, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		log.Fatal().Msgf("Invalid --date format: %q (expected YYYY-MM-DD)", dateStr)
+	}
+	log.Info().Msgf("Using reference date: %s", dateStr)
+	return parsed
+}
+
+// stackInput is a stack to report, read from a stack file, an SBOM or an image.
+type stackInput struct {
+	Title string
+	// AppID is the app_id of a stack file, which keys the app in the output of several stacks
+	AppID string
+	// File is the file the stack was read from, used as the location of the SARIF results
+	File string
+	// Locations maps the stack item names to where they are defined, when File is a stack file
+	Locations map[string]stackLocation
+	Stack     []stackItem
+	// Policies are the rules of the policies: section of a stack file
+	Policies []policyRule
+	// Scoring is the debt scoring model of the stack
+	Scoring utilities.ScoringModel
+	// Notify are the webhooks of the notify: section of a stack file
+	Notify []utilities.NotifyTarget
+	// Unmapped are the SBOM components that could not be mapped to a product
+	Unmapped []unmappedComponent
+}
+
+// appKey returns the key of a stack in the output of several stacks: its app_id, or its title
+// when it has none.
+func (input stackInput) appKey() string {
+	if input.AppID != "" {
+		return input.AppID
+	}
+	return input.Title
+}
+
+// stackResult is a stack evaluated against the endoflife.date data.
+type stackResult struct {
+	stackInput
+	Rows       []stackTableRow
+	ErrorOut   bool
+	Violations []stackViolation
+	Score      stackScore
+}
+
+// evaluateStack evaluates the stack items of input, then its policies and waivers, as of the today reference date.
+// It returns an error when an item cannot be evaluated (see getStackTableRows).
+func evaluateStack(input stackInput, today time.Time) (stackResult, error) {
+	rows, violations, err := getStackTableRows(input.Stack, today, input.Scoring)
+	if err != nil {
+		return stackResult{}, err
+	}
+	violations = append(violations, evaluatePolicies(input.Policies, rows)...)
+	violations = applyWaivers(input.Stack, rows, violations, today)
+	errorOut := slices.ContainsFunc(violations, stackViolation.failsStrict)
+	return stackResult{stackInput: input, Rows: rows, ErrorOut: errorOut, Violations: violations, Score: computeStackScore(rows, input.Scoring.Bands)}, nil
+}
+
+// checkDistinctApps returns an error when two stacks have the same app key.
+func checkDistinctApps(inputs []stackInput) error {
+	files := map[string]string{}
+	for _, input := range inputs {
+		if previous, ok := files[input.appKey()]; ok {
+			return fmt.Errorf("Duplicate app %q in %s and %s: set a distinct app_id", input.appKey(), previous, input.File)
 		}
+		files[input.appKey()] = input.File
+	}
+	return nil
+}
+
+// reportStack reports a single stack (see reportStacks).
+func reportStack(cmd *cobra.Command, input stackInput) {
+	reportStacks(cmd, []stackInput{input})
+}
 
-		if hasErrors {
-			log.Fatal().Msg("Validation failed: please fix the errors above")
+// reportStacks evaluates the stack items against the endoflife.date data as of the --date
+// reference date, then prints the report in the --format output format, along with the SBOM
+// components that could not be mapped, if any. Several stacks are reported app by app, followed
+// by their combined score. With --strict, it exits with an error when a product of any stack is
+// past EOL or not in its required version.
+func reportStacks(cmd *cobra.Command, inputs []stackInput) {
+	strict, _ := cmd.Flags().GetBool("strict")
+	format := outputFormat(cmd)
+	alarms := alarmDays(cmd)
+	multi := len(inputs) > 1
+	if multi && format == "cyclonedx" {
+		log.Fatal().Msg("The cyclonedx format describes a single stack, check the stack files one by one")
+	}
+	if err := checkDistinctApps(inputs); err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+
+	utilities.AnalyzeCacheProductsValidity(cmd)
+	today := referenceDate(cmd)
+	results := make([]stackResult, 0, len(inputs))
+	var allRows []stackTableRow
+	for _, input := range inputs {
+		result, err := evaluateStack(input, today)
+		if err != nil {
+			log.Fatal().Msg(err.Error())
 		}
+		results = append(results, result)
+		allRows = append(allRows, result.Rows...)
+	}
+	combined := computeStackScore(allRows, utilities.Scoring.Bands)
+	if record, _ := cmd.Flags().GetBool("record"); record {
+		if err := recordRuns(results, today); err != nil {
+			log.Fatal().Err(err).Msg("Error recording the check history")
+		}
+	}
 
-		utilities.AnalyzeCacheProductsValidity(cmd)
-		today := time.Now()
-		if dateStr, _ := cmd.Flags().GetString("date"); dateStr != "" {
-			parsed, err := time.Parse("2006-01-02", dateStr)
-			if err != nil {
-				log.Fatal().Msgf("Invalid --date format: %q (expected YYYY-MM-DD)", dateStr)
-			}
-			today = parsed
-			log.Info().Msgf("Using reference date: %s", dateStr)
-		}
-		rows, errorOut, violations := getStackTableRows(config.Stack, today)
-		score := computeStackScore(rows)
-
-		if jsonOutput {
-			output := struct {
-				Title              string          `json:"title"`
-				Score              []stackScore    `json:"score"`
-				SoftwareComponents []stackTableRow `json:"software_components"`
-			}{
-				Title:              config.AppName,
-				Score:              []stackScore{score},
-				SoftwareComponents: rows,
+	switch format {
+	case "cyclonedx":
+		r := results[0]
+		if err := writeCycloneDX(os.Stdout, r.Title, r.Rows, r.Score, today); err != nil {
+			log.Fatal().Msg("Error generating CycloneDX output: " + err.Error())
+		}
+	case "sarif":
+		if err := writeSarif(os.Stdout, results); err != nil {
+			log.Fatal().Msg("Error generating SARIF output: " + err.Error())
+		}
+	case "junit":
+		if err := writeJUnit(os.Stdout, results); err != nil {
+			log.Fatal().Msg("Error generating JUnit output: " + err.Error())
+		}
+	case "ics":
+		if err := writeCalendar(os.Stdout, results, today, alarms); err != nil {
+			log.Fatal().Msg("Error generating iCalendar output: " + err.Error())
+		}
+	case "json":
+		jsonData, err := json.MarshalIndent(checkOutput(results, combined), "", "  ")
+		if err != nil {
+			log.Fatal().Msg("Error generating JSON output: " + err.Error())
+		}
+		fmt.Println(string(jsonData))
+	default:
+		for i, r := range results {
+			if i > 0 {
+				_, _ = lipgloss.Println()
 			}
-			jsonData, err := json.MarshalIndent(output, "", "  ")
-			if err != nil {
-				log.Fatal().Msg("Error generating JSON output: " + err.Error())
+			title := r.Title
+			if multi && r.AppID != "" && r.AppID != r.Title {
+				title += " (" + r.AppID + ")"
 			}
-			fmt.Println(string(jsonData))
-		} else {
-			tableStr := renderStackTable(rows)
 			styledTitle := lipgloss.NewStyle().
 				Bold(true).Foreground(lipgloss.Color("#FFFF88")).
 				Background(lipgloss.Color("#5F5FFF")).
-				Render("## " + config.AppName)
+				Render("## " + title)
 			_, _ = lipgloss.Println(styledTitle)
-			_, _ = lipgloss.Println(renderStackScore(score))
-			_, _ = lipgloss.Println(tableStr)
+			_, _ = lipgloss.Println(renderStackScore(r.Score))
+			_, _ = lipgloss.Println(renderStackTable(r.Rows, r.Scoring.Bands))
+			if len(r.Unmapped) > 0 {
+				_, _ = lipgloss.Println()
+				_, _ = lipgloss.Println(renderUnmappedTable(r.Unmapped))
+			}
 		}
+		if multi {
+			_, _ = lipgloss.Println()
+			_, _ = lipgloss.Println(lipgloss.NewStyle().
+				Bold(true).Foreground(lipgloss.Color("#FFFF88")).
+				Background(lipgloss.Color("#5F5FFF")).
+				Render(fmt.Sprintf("## Combined: %d apps, %d software components", len(results), len(allRows))))
+			_, _ = lipgloss.Println(renderStackScore(combined))
+		}
+	}
 
-		if len(violations) > 0 {
-			for _, violation := range violations {
-				log.Error().Msg(violation)
+	errorOut := false
+	for _, r := range results {
+		if len(r.Unmapped) > 0 {
+			log.Warn().Msgf("%d SBOM components could not be mapped to an endoflife.date product", len(r.Unmapped))
+		}
+		for _, violation := range r.Violations {
+			entry := log.Error()
+			if violation.Warning {
+				entry = log.Warn()
+			}
+			if multi {
+				entry = entry.Str("app", r.appKey())
 			}
+			entry.Msg(violation.Message)
 		}
+		errorOut = errorOut || r.ErrorOut
+	}
 
-		if errorOut && strict {
-			log.Fatal().Msg("One or more products are past EOL or not in latest version. Exiting with error due to strict mode.")
-		}
-	},
+	if noNotify, _ := cmd.Flags().GetBool("no-notify"); !noNotify {
+		notifyResults(results, combined, today)
+	}
+
+	if errorOut && strict {
+		log.Fatal().Msg("One or more products are past EOL, not in their required version or break an error policy. Exiting with error due to strict mode.")
+	}
+}
+
+// checkOutput returns the JSON output of the results: the app of a single stack, or the apps of
+// several stacks keyed by app, with their combined score.
+func checkOutput(results []stackResult, combined stackScore) any {
+	type appOutput struct {
+		Title              string                 `json:"title"`
+		File               string                 `json:"file,omitempty"`
+		Score              []stackScore           `json:"score"`
+		ScoringModel       utilities.ScoringModel `json:"scoring_model"`
+		SoftwareComponents []stackTableRow        `json:"software_components"`
+		UnmappedComponents []unmappedComponent    `json:"unmapped_components,omitempty"`
+	}
+	if len(results) == 1 {
+		r := results[0]
+		return appOutput{Title: r.Title, Score: []stackScore{r.Score}, ScoringModel: r.Scoring, SoftwareComponents: r.Rows, UnmappedComponents: r.Unmapped}
+	}
+	apps := map[string]appOutput{}
+	for _, r := range results {
+		apps[r.appKey()] = appOutput{Title: r.Title, File: r.File, Score: []stackScore{r.Score}, ScoringModel: r.Scoring, SoftwareComponents: r.Rows, UnmappedComponents: r.Unmapped}
+	}
+	return struct {
+		Score        []stackScore           `json:"score"`
+		ScoringModel utilities.ScoringModel `json:"scoring_model"`
+		Apps         map[string]appOutput   `json:"apps"`
+	}{Score: []stackScore{combined}, ScoringModel: utilities.Scoring, Apps: apps}
+}
+
+// checkSbom maps the components of an SBOM to endoflife.date products through their purl and
+// CPE identifiers, then reports them like a stack.
+func checkSbom(cmd *cobra.Command, sbomPath string) {
+	title, components, err := readSbom(sbomPath)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Error reading the SBOM %s", sbomPath)
+	}
+	if title == "" {
+		title = filepath.Base(sbomPath)
+	}
+
+	utilities.AnalyzeCacheProductsValidity(cmd)
+	productsPath, err := utilities.GetProductsPath()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error retrieving products path")
+	}
+	products, err := utilities.GetProductsWithCacheRefresh(cmd, productsPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error retrieving products from cache")
+	}
+	identifiers, err := utilities.GetIdentifiersWithCacheRefresh(cmd)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error retrieving product identifiers")
+	}
+
+	found, unmapped := mapSbomComponents(components, identifiers)
+	items := resolveDiscoveries(found, products)
+	stack := make([]stackItem, 0, len(items))
+	for _, item := range items {
+		stack = append(stack, item.stackItem)
+	}
+	log.Info().Msgf("Mapped %d of %d SBOM components to %d products", len(components)-len(unmapped), len(components), len(stack))
+	reportStack(cmd, stackInput{Title: title, File: sbomPath, Stack: stack, Unmapped: unmapped, Scoring: utilities.Scoring})
 }
@@ -0,0 +1,144 @@
+package check
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opt-nc/geol/v2/utilities"
+)
+
+// cycloneDXSpecVersion is the CycloneDX specification version of the BOMs geol writes.
+const cycloneDXSpecVersion = "1.5"
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cycloneDXOutputComponent struct {
+	Type               string                       `json:"type"`
+	BomRef             string                       `json:"bom-ref,omitempty"`
+	Name               string                       `json:"name"`
+	Version            string                       `json:"version,omitempty"`
+	ExternalReferences []cycloneDXExternalReference `json:"externalReferences,omitempty"`
+	Properties         []cycloneDXProperty          `json:"properties,omitempty"`
+}
+
+type cycloneDXBom struct {
+	BomFormat    string `json:"bomFormat"`
+	SpecVersion  string `json:"specVersion"`
+	SerialNumber string `json:"serialNumber"`
+	Version      int    `json:"version"`
+	Metadata     struct {
+		Timestamp string `json:"timestamp"`
+		Tools     struct {
+			Components []cycloneDXOutputComponent `json:"components"`
+		} `json:"tools"`
+		Component  cycloneDXOutputComponent `json:"component"`
+		Properties []cycloneDXProperty      `json:"properties"`
+	} `json:"metadata"`
+	Components []cycloneDXOutputComponent `json:"components"`
+}
+
+// cycloneDXComponentTypes maps endoflife.date categories to CycloneDX component types.
+var cycloneDXComponentTypes = map[string]string{
+	"os":        "operating-system",
+	"framework": "framework",
+	"lang":      "platform",
+	"device":    "device",
+}
+
+// canonicalProductName returns the endoflife.date product name of an id_eol, which may be an
+// alias, or id_eol itself when it cannot be resolved.
+func canonicalProductName(idEol string) string {
+	productsPath, err := utilities.GetProductsPath()
+	if err != nil {
+		return idEol
+	}
+	products, err := utilities.GetProductsWithCacheRefresh(nil, productsPath)
+	if err != nil {
+		return idEol
+	}
+	if name, found := resolveProductName(products, idEol); found {
+		return name
+	}
+	return idEol
+}
+
+// productCategory returns the endoflife.date category of a product, or an empty string.
+func productCategory(prod string) string {
+	body, err := fetchProductBody(prod)
+	if err != nil {
+		return ""
+	}
+	var payload struct {
+		Result struct {
+			Category string `json:"category"`
+		} `json:"result"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	return payload.Result.Category
+}
+
+// writeCycloneDX writes the evaluated stack as a CycloneDX JSON BOM. Every component carries the
+// geol evaluation as geol:* properties, the stack score is a property of the BOM metadata.
+func writeCycloneDX(w io.Writer, title string, rows []stackTableRow, score stackScore, referenceDate time.Time) error {
+	var bom cycloneDXBom
+	bom.BomFormat = "CycloneDX"
+	bom.SpecVersion = cycloneDXSpecVersion
+	bom.SerialNumber = "urn:uuid:" + uuid.NewString()
+	bom.Version = 1
+	bom.Metadata.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	bom.Metadata.Tools.Components = []cycloneDXOutputComponent{{Type: "application", Name: "geol", Version: utilities.Version}}
+	bom.Metadata.Component = cycloneDXOutputComponent{Type: "application", Name: title}
+	bom.Metadata.Properties = []cycloneDXProperty{
+		{Name: "geol:stack_score", Value: strconv.Itoa(score.Value)},
+		{Name: "geol:stack_score_message", Value: score.Message},
+		{Name: "geol:reference_date", Value: referenceDate.Format("2006-01-02")},
+	}
+
+	bom.Components = make([]cycloneDXOutputComponent, 0, len(rows))
+	for _, r := range rows {
+		prod := canonicalProductName(r.IdEol)
+		componentType, ok := cycloneDXComponentTypes[productCategory(prod)]
+		if !ok {
+			componentType = "application"
+		}
+		properties := []cycloneDXProperty{
+			{Name: "geol:id_eol", Value: r.IdEol},
+			{Name: "geol:status", Value: r.Status},
+			{Name: "geol:eol_date", Value: r.EolDate},
+			{Name: "geol:days_left", Value: r.Days},
+			{Name: "geol:is_latest", Value: strconv.FormatBool(r.IsLatest)},
+			{Name: "geol:latest_version", Value: r.LatestVersion},
+			{Name: "geol:debt_score", Value: strconv.Itoa(r.DebtScore)},
+			{Name: "geol:is_lts", Value: strconv.FormatBool(r.IsLts)},
+			{Name: "geol:is_latest_lts", Value: strconv.FormatBool(r.IsLatestLts)},
+		}
+		if r.LtsStrategy != "" {
+			properties = append(properties, cycloneDXProperty{Name: "geol:lts_strategy", Value: r.LtsStrategy})
+		}
+		bom.Components = append(bom.Components, cycloneDXOutputComponent{
+			Type:               componentType,
+			BomRef:             "geol:" + r.Software,
+			Name:               r.Software,
+			Version:            r.Version,
+			ExternalReferences: []cycloneDXExternalReference{{Type: "website", URL: "https://endoflife.date/" + prod}},
+			Properties:         properties,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bom)
+}
@@ -0,0 +1,312 @@
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/Masterminds/semver/v3"
+	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	CheckCmd.AddCommand(DiffCmd)
+	DiffCmd.Flags().String("base", "", "Stack file or saved 'geol check --json' output before the change")
+	DiffCmd.Flags().String("head", ".geol.yaml", "Stack file or saved 'geol check --json' output after the change")
+	DiffCmd.Flags().String("format", "table", "Output format: table, json, markdown")
+	DiffCmd.Flags().StringP("env", "e", "", "Environment whose overlay is applied to the stack files (see environments in the stack file)")
+	DiffCmd.Flags().StringP("date", "d", "", "Reference date for EOL calculations (format YYYY-MM-DD, default: today)")
+	_ = DiffCmd.MarkFlagRequired("base")
+}
+
+// DiffCmd represents the check diff command
+var DiffCmd = &cobra.Command{
+	Use:     "diff",
+	Aliases: []string{"df"},
+	Short:   "Show the health impact of a change of a stack.",
+	Long: `The 'diff' command compares two evaluations of a stack: the added, removed, upgraded and downgraded components, their status transitions (e.g. EOL → OK), and the change of each debt score and of the stack score.
+Each side is either a stack file, evaluated like 'geol check', or a saved 'geol check --json' output. Use --format markdown to post the diff as a pull request comment.`,
+	Example: `git show origin/main:.geol.yaml > base.geol.yaml
+geol check diff --base base.geol.yaml
+geol check diff --base main.json --head pr.json --format json
+geol check diff --base base.geol.yaml --head .geol.yaml --format markdown > comment.md`,
+	Run: func(cmd *cobra.Command, args []string) {
+		basePath, _ := cmd.Flags().GetString("base")
+		headPath, _ := cmd.Flags().GetString("head")
+		format, _ := cmd.Flags().GetString("format")
+		if format != "table" && format != "json" && format != "markdown" {
+			log.Fatal().Msgf("Unknown output format %q: expected table, json or markdown", format)
+		}
+
+		base := readDiffSide(cmd, basePath)
+		head := readDiffSide(cmd, headPath)
+		diff := diffStacks(base, head)
+
+		var err error
+		switch format {
+		case "json":
+			var jsonData []byte
+			jsonData, err = json.MarshalIndent(diff, "", "  ")
+			if err == nil {
+				fmt.Println(string(jsonData))
+			}
+		case "markdown":
+			err = writeDiffMarkdown(os.Stdout, diff)
+		default:
+			renderDiffTable(diff)
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error generating the diff")
+		}
+	},
+}
+
+// diffSide is one side of a diff: the title, stack score and components of an evaluation.
+type diffSide struct {
+	Title      string
+	Score      stackScore
+	Components []stackTableRow
+}
+
+// readDiffSide evaluates a stack file, or reads a saved 'geol check --json' output. The components
+// of a multi-app output are prefixed with their app key.
+func readDiffSide(cmd *cobra.Command, path string) diffSide {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Error reading %s", path)
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		env, _ := cmd.Flags().GetString("env")
+		input := readStackFile(path, env)
+		utilities.AnalyzeCacheProductsValidity(cmd)
+		result, err := evaluateStack(input, referenceDate(cmd))
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+		return diffSide{Title: result.Title, Score: result.Score, Components: result.Rows}
+	}
+
+	type appOutput struct {
+		Title              string          `json:"title"`
+		Score              []stackScore    `json:"score"`
+		SoftwareComponents []stackTableRow `json:"software_components"`
+	}
+	var output struct {
+		appOutput
+		Apps map[string]appOutput `json:"apps"`
+	}
+	if err := json.Unmarshal(data, &output); err != nil {
+		log.Fatal().Err(err).Msgf("Error reading %s as a 'geol check --json' output", path)
+	}
+	if len(output.Score) == 0 {
+		log.Fatal().Msgf("%s is not a 'geol check --json' output: no score", path)
+	}
+	side := diffSide{Title: output.Title, Score: output.Score[0], Components: output.SoftwareComponents}
+	if output.Apps != nil {
+		keys := make([]string, 0, len(output.Apps))
+		for key := range output.Apps {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		side.Title = strings.Join(keys, ", ")
+		for _, key := range keys {
+			for _, c := range output.Apps[key].SoftwareComponents {
+				c.Software = key + ": " + c.Software
+				side.Components = append(side.Components, c)
+			}
+		}
+	}
+	return side
+}
+
+// Kinds of component changes, in their display order.
+const (
+	changeRemoved    = "removed"
+	changeAdded      = "added"
+	changeUpgraded   = "upgraded"
+	changeDowngraded = "downgraded"
+	changeVersion    = "version changed"
+	changeStatus     = "status changed"
+)
+
+var changeOrder = map[string]int{changeRemoved: 0, changeAdded: 1, changeUpgraded: 2, changeDowngraded: 3, changeVersion: 4, changeStatus: 5}
+
+// componentChange is the change of a component between the base and the head of a diff. The base
+// fields are empty for added components, and the head fields for removed ones.
+type componentChange struct {
+	Software        string `json:"software"`
+	Change          string `json:"change"`
+	BaseVersion     string `json:"base_version,omitempty"`
+	HeadVersion     string `json:"head_version,omitempty"`
+	BaseStatus      string `json:"base_status,omitempty"`
+	HeadStatus      string `json:"head_status,omitempty"`
+	BaseDebtScore   *int   `json:"base_debt_score,omitempty"`
+	HeadDebtScore   *int   `json:"head_debt_score,omitempty"`
+	DebtScoreChange int    `json:"debt_score_change"`
+}
+
+// stackDiff is the diff of two evaluations of a stack.
+type stackDiff struct {
+	BaseTitle   string            `json:"base_title"`
+	HeadTitle   string            `json:"head_title"`
+	BaseScore   stackScore        `json:"base_score"`
+	HeadScore   stackScore        `json:"head_score"`
+	ScoreChange int               `json:"score_change"`
+	Changes     []componentChange `json:"changes"`
+	Unchanged   int               `json:"unchanged"`
+}
+
+// diffStacks matches the components of base and head by name, and returns their changes.
+func diffStacks(base, head diffSide) stackDiff {
+	diff := stackDiff{
+		BaseTitle: base.Title, HeadTitle: head.Title,
+		BaseScore: base.Score, HeadScore: head.Score,
+		ScoreChange: head.Score.Value - base.Score.Value,
+		Changes:     []componentChange{},
+	}
+	headByName := map[string]stackTableRow{}
+	for _, c := range head.Components {
+		headByName[c.Software] = c
+	}
+	baseNames := map[string]bool{}
+	for _, b := range base.Components {
+		baseNames[b.Software] = true
+		change := componentChange{Software: b.Software, BaseVersion: b.Version, BaseStatus: b.Status, BaseDebtScore: &b.DebtScore}
+		h, ok := headByName[b.Software]
+		if !ok {
+			change.Change = changeRemoved
+			change.DebtScoreChange = -b.DebtScore
+			diff.Changes = append(diff.Changes, change)
+			continue
+		}
+		change.HeadVersion, change.HeadStatus, change.HeadDebtScore = h.Version, h.Status, &h.DebtScore
+		change.DebtScoreChange = h.DebtScore - b.DebtScore
+		switch {
+		case b.Version != h.Version:
+			change.Change = versionChange(b.Version, h.Version)
+		case b.Status != h.Status || b.DebtScore != h.DebtScore:
+			change.Change = changeStatus
+		default:
+			diff.Unchanged++
+			continue
+		}
+		diff.Changes = append(diff.Changes, change)
+	}
+	for _, h := range head.Components {
+		if !baseNames[h.Software] {
+			diff.Changes = append(diff.Changes, componentChange{
+				Software: h.Software, Change: changeAdded,
+				HeadVersion: h.Version, HeadStatus: h.Status, HeadDebtScore: &h.DebtScore, DebtScoreChange: h.DebtScore,
+			})
+		}
+	}
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		if diff.Changes[i].Change != diff.Changes[j].Change {
+			return changeOrder[diff.Changes[i].Change] < changeOrder[diff.Changes[j].Change]
+		}
+		return diff.Changes[i].Software < diff.Changes[j].Software
+	})
+	return diff
+}
+
+// versionChange returns whether going from one version to another is an upgrade or a downgrade,
+// or only a version change when the versions cannot be compared.
+func versionChange(from, to string) string {
+	f, errF := semver.NewVersion(from)
+	t, errT := semver.NewVersion(to)
+	switch {
+	case errF != nil || errT != nil:
+		return changeVersion
+	case t.GreaterThan(f):
+		return changeUpgraded
+	case t.LessThan(f):
+		return changeDowngraded
+	default:
+		return changeVersion
+	}
+}
+
+// transition formats the transition of a field, or its value when it did not change.
+func transition(from, to string) string {
+	switch {
+	case from == "":
+		return to
+	case to == "", from == to:
+		return from
+	default:
+		return from + " → " + to
+	}
+}
+
+// debtScoreTransition formats the transition of a debt score.
+func (c componentChange) debtScoreTransition() string {
+	switch {
+	case c.BaseDebtScore == nil:
+		return fmt.Sprint(*c.HeadDebtScore)
+	case c.HeadDebtScore == nil:
+		return fmt.Sprint(*c.BaseDebtScore)
+	default:
+		return transition(fmt.Sprint(*c.BaseDebtScore), fmt.Sprint(*c.HeadDebtScore))
+	}
+}
+
+// title returns the title of a diff.
+func (d stackDiff) title() string {
+	if d.BaseTitle == d.HeadTitle {
+		return d.HeadTitle
+	}
+	return d.BaseTitle + " → " + d.HeadTitle
+}
+
+// renderDiffTable prints the diff as a table.
+func renderDiffTable(d stackDiff) {
+	_, _ = lipgloss.Println(lipgloss.NewStyle().
+		Bold(true).Foreground(lipgloss.Color("#FFFF88")).
+		Background(lipgloss.Color("#5F5FFF")).
+		Render("## Diff: " + d.title()))
+	_, _ = lipgloss.Printf("Stack Debt Score: %s → %s (%s)\n", renderScoreBand(d.BaseScore), renderScoreBand(d.HeadScore), scoreDelta(d.ScoreChange))
+	if len(d.Changes) == 0 {
+		fmt.Printf("No component changed (%d unchanged).\n", d.Unchanged)
+		return
+	}
+	t := newStackTable("Change", "Software", "Version", "Status", "Debt Score", "Δ")
+	for _, c := range d.Changes {
+		t.Row(c.Change, c.Software, transition(c.BaseVersion, c.HeadVersion), transition(c.BaseStatus, c.HeadStatus), c.debtScoreTransition(), scoreDelta(c.DebtScoreChange))
+	}
+	_, _ = lipgloss.Println(t.Render())
+	fmt.Printf("%d component(s) unchanged.\n", d.Unchanged)
+}
+
+// writeDiffMarkdown writes the diff as markdown, fit for a pull request comment.
+func writeDiffMarkdown(w io.Writer, d stackDiff) error {
+	icons := map[string]string{"green": "🟢", "orange": "🟠", "red": "🔴"}
+	var b strings.Builder
+	fmt.Fprintf(&b, "### geol stack diff: %s\n\n", d.title())
+	fmt.Fprintf(&b, "**Stack Debt Score:** %s %d/100 → %s %d/100 (**%s**)\n\n",
+		icons[d.BaseScore.Color], d.BaseScore.Value, icons[d.HeadScore.Color], d.HeadScore.Value, scoreDelta(d.ScoreChange))
+	if len(d.Changes) == 0 {
+		fmt.Fprintf(&b, "No component changed (%d unchanged).\n", d.Unchanged)
+	} else {
+		b.WriteString("| Change | Software | Version | Status | Debt Score | Δ |\n")
+		b.WriteString("|--------|----------|---------|--------|------------|---|\n")
+		for _, c := range d.Changes {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", c.Change, markdownCell(c.Software),
+				markdownCell(transition(c.BaseVersion, c.HeadVersion)), transition(c.BaseStatus, c.HeadStatus), c.debtScoreTransition(), scoreDelta(c.DebtScoreChange))
+		}
+		fmt.Fprintf(&b, "\n%d component(s) unchanged.\n", d.Unchanged)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell escapes the pipes of a markdown table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
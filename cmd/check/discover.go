@@ -0,0 +1,637 @@
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	CheckCmd.AddCommand(DiscoverCmd)
+	DiscoverCmd.Flags().StringP("output", "o", "", "Path to the output file (default: .geol.yaml in the scanned directory)")
+	DiscoverCmd.Flags().StringP("app-name", "a", "", "Application name to use when creating a new file (default: the directory name)")
+	DiscoverCmd.Flags().Bool("dry-run", false, "Print the resulting file instead of writing it")
+}
+
+// DiscoverCmd represents the check discover command
+var DiscoverCmd = &cobra.Command{
+	Use:     "discover [path]",
+	Aliases: []string{"d"},
+	Short:   "Scan a repository and propose the stack of the check configuration file",
+	Long: `The discover command scans a repository and proposes the stack items of the check configuration file, with their name, version and id_eol resolved against the local products and aliases cache.
+
+The following files are read:
+- Dockerfile and Containerfile FROM lines
+- go.mod go directive
+- package.json engines, .nvmrc, .python-version and .tool-versions
+- pom.xml parent versions
+- GitHub workflows setup-* actions versions
+- docker-compose and compose files images
+
+When the output file already exists, discovered items are merged into it: versions of known products are updated, new products are appended, and every other field (skip, manual_eol, always-latest, lts_strategy...) and item is kept as is.`,
+	Example: `geol check discover
+geol check discover ./my-repo --dry-run
+geol check discover ./my-repo --output stack.yaml --app-name MySuperApp`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		root := "."
+		if len(args) == 1 {
+			root = args[0]
+		}
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			log.Fatal().Msgf("%s is not a directory", root)
+		}
+		outputPath, _ := cmd.Flags().GetString("output")
+		if outputPath == "" {
+			outputPath = filepath.Join(root, ".geol.yaml")
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		appName, _ := cmd.Flags().GetString("app-name")
+
+		utilities.AnalyzeCacheProductsValidity(cmd)
+		productsPath, err := utilities.GetProductsPath()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error retrieving products path")
+		}
+		products, err := utilities.GetProductsWithCacheRefresh(cmd, productsPath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error retrieving products from cache")
+		}
+
+		found, err := scanRepository(root)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("Error scanning %s", root)
+		}
+		items := resolveDiscoveries(found, products)
+		log.Info().Msgf("Discovered %d stack items in %s", len(items), root)
+
+		doc, err := loadOrCreateStackDocument(outputPath, root, appName)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("Error reading %s", outputPath)
+		}
+		if err := mergeDiscoveredItems(doc, items, products); err != nil {
+			log.Fatal().Err(err).Msgf("Error merging into %s", outputPath)
+		}
+
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			log.Fatal().Err(err).Msg("Error generating YAML")
+		}
+		if err := encoder.Close(); err != nil {
+			log.Fatal().Err(err).Msg("Error generating YAML")
+		}
+
+		if dryRun {
+			fmt.Print(buf.String())
+			return
+		}
+		if err := os.WriteFile(outputPath, buf.Bytes(), 0o644); err != nil {
+			log.Fatal().Err(err).Msgf("Error writing %s", outputPath)
+		}
+		log.Info().Msgf("Stack written to %s, review it then run 'geol check --file %s'", outputPath, outputPath)
+	},
+}
+
+// discovery is a product version found in a repository file, before resolution against the cache.
+type discovery struct {
+	// candidates are the possible endoflife.date ids, tried in order
+	candidates []string
+	version    string
+	source     string
+}
+
+// discoveredItem is a discovery resolved to an endoflife.date product and release cycle.
+type discoveredItem struct {
+	stackItem
+	source string
+}
+
+// discoveryAliases maps image, tool and package names to their endoflife.date product id, when
+// the product cache does not know them as aliases.
+var discoveryAliases = map[string]string{
+	"node":                       "nodejs",
+	"golang":                     "go",
+	"postgres":                   "postgresql",
+	"mongo":                      "mongodb",
+	"httpd":                      "apache-http-server",
+	"amazoncorretto":             "amazon-corretto",
+	"maven":                      "apache-maven",
+	"kubectl":                    "kubernetes",
+	"rockylinux":                 "rocky-linux",
+	"temurin":                    "eclipse-temurin",
+	"corretto":                   "amazon-corretto",
+	"zulu":                       "azul-zulu",
+	"liberica":                   "bellsoft-liberica",
+	"semeru":                     "ibm-semeru-runtime",
+	"microsoft":                  "microsoft-build-of-openjdk",
+	"oracle":                     "oracle-jdk",
+	"spring-boot-starter-parent": "spring-boot",
+	"spring-boot-dependencies":   "spring-boot",
+	"quarkus-bom":                "quarkus-framework",
+}
+
+// setupActions maps the GitHub setup actions to the input holding the version and the product id.
+var setupActions = map[string]struct{ input, product string }{
+	"actions/setup-node":          {"node-version", "nodejs"},
+	"actions/setup-python":        {"python-version", "python"},
+	"actions/setup-go":            {"go-version", "go"},
+	"actions/setup-java":          {"java-version", ""}, // product from the distribution input
+	"actions/setup-dotnet":        {"dotnet-version", "dotnet"},
+	"ruby/setup-ruby":             {"ruby-version", "ruby"},
+	"shivammathur/setup-php":      {"php-version", "php"},
+	"hashicorp/setup-terraform":   {"terraform_version", "terraform"},
+	"gradle/actions/setup-gradle": {"gradle-version", "gradle"},
+	"pnpm/action-setup":           {"version", "pnpm"},
+	"oven-sh/setup-bun":           {"bun-version", "bun"},
+	"denoland/setup-deno":         {"deno-version", "deno"},
+}
+
+// versionPattern extracts the leading numeric version of a tag (e.g. 3.12 in 3.12-slim).
+var versionPattern = regexp.MustCompile(`^v?(\d+(?:\.\d+)*)`)
+
+// skippedDirs are never scanned.
+var skippedDirs = map[string]bool{".git": true, "node_modules": true, "vendor": true, "target": true, "dist": true, ".venv": true}
+
+// scanRepository walks root and collects the discoveries of every supported file.
+func scanRepository(root string) ([]discovery, error) {
+	var found []discovery
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skippedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		name := d.Name()
+		lower := strings.ToLower(name)
+		var parse func([]byte, string) []discovery
+		switch {
+		case strings.HasPrefix(lower, "dockerfile") || strings.HasPrefix(lower, "containerfile") || strings.HasSuffix(lower, ".dockerfile"):
+			parse = parseDockerfile
+		case name == "go.mod":
+			parse = parseGoMod
+		case name == "package.json":
+			parse = parsePackageJSON
+		case name == ".nvmrc" || name == ".node-version":
+			parse = toolVersionFile("nodejs")
+		case name == ".python-version":
+			parse = toolVersionFile("python")
+		case name == ".tool-versions":
+			parse = parseToolVersions
+		case name == "pom.xml":
+			parse = parsePom
+		case (strings.HasSuffix(lower, ".yml") || strings.HasSuffix(lower, ".yaml")) && filepath.Base(filepath.Dir(path)) == "workflows":
+			parse = parseWorkflow
+		case strings.HasPrefix(lower, "docker-compose") || strings.HasPrefix(lower, "compose."):
+			parse = parseCompose
+		default:
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Skipping %s", rel)
+			return nil
+		}
+		found = append(found, parse(data, rel)...)
+		return nil
+	})
+	return found, err
+}
+
+// extractVersion returns the leading numeric version of s, ignoring constraint operators
+// (e.g. 18 in ">=18 <21"), or an empty string.
+func extractVersion(s string) string {
+	if m := versionPattern.FindStringSubmatch(strings.TrimLeft(s, "^~>=< ")); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// parseDockerfile reads the FROM lines, ignoring scratch, build stage references and ARG values.
+func parseDockerfile(data []byte, source string) []discovery {
+	var found []discovery
+	stages := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+		args := fields[1:]
+		for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			continue
+		}
+		image := args[0]
+		if len(args) >= 3 && strings.EqualFold(args[1], "AS") {
+			stages[strings.ToLower(args[2])] = true
+		}
+		if stages[strings.ToLower(image)] && !strings.Contains(image, ":") {
+			continue
+		}
+		if d, ok := imageDiscovery(image, source); ok {
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+// imageDiscovery turns a container image reference (registry/path/name:tag@digest) into a discovery.
+func imageDiscovery(image, source string) (discovery, bool) {
+	if image == "scratch" || strings.Contains(image, "$") {
+		return discovery{}, false
+	}
+	image, _, _ = strings.Cut(image, "@")
+	repository, tag, hasTag := strings.Cut(image, ":")
+	// A colon in the last path element only: registry ports (host:5000/name) are not tags
+	if hasTag && strings.Contains(tag, "/") {
+		repository, tag = image, ""
+	}
+	version := extractVersion(tag)
+	if version == "" {
+		log.Debug().Msgf("%s: no version in image %s", source, image)
+		return discovery{}, false
+	}
+	parts := strings.Split(strings.ToLower(repository), "/")
+	name := parts[len(parts)-1]
+	candidates := []string{name}
+	switch {
+	case slices.Contains(parts, "dotnet"):
+		candidates = []string{"dotnet"}
+	case strings.HasPrefix(name, "ubi") && extractVersion(name) != "":
+		candidates, version = []string{"rhel"}, extractVersion(name)
+	}
+	return discovery{candidates: candidates, version: version, source: source}, true
+}
+
+// parseGoMod reads the go directive.
+func parseGoMod(data []byte, source string) []discovery {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "go" {
+			return []discovery{{candidates: []string{"go"}, version: extractVersion(fields[1]), source: source}}
+		}
+	}
+	return nil
+}
+
+// parsePackageJSON reads the engines constraints, keeping the lowest version they mention.
+func parsePackageJSON(data []byte, source string) []discovery {
+	var pkg struct {
+		Engines map[string]string `json:"engines"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		log.Warn().Err(err).Msgf("Skipping %s", source)
+		return nil
+	}
+	var found []discovery
+	for engine, constraint := range pkg.Engines {
+		if version := extractVersion(constraint); version != "" {
+			found = append(found, discovery{candidates: []string{engine}, version: version, source: source})
+		}
+	}
+	return found
+}
+
+// toolVersionFile returns a parser for single-version files such as .nvmrc or .python-version.
+func toolVersionFile(product string) func([]byte, string) []discovery {
+	return func(data []byte, source string) []discovery {
+		version := extractVersion(strings.TrimSpace(string(data)))
+		if version == "" {
+			log.Warn().Msgf("%s: no numeric version found (aliases such as lts/* are not supported)", source)
+			return nil
+		}
+		return []discovery{{candidates: []string{product}, version: version, source: source}}
+	}
+}
+
+// parseToolVersions reads an asdf/mise .tool-versions file. Java entries carry their
+// distribution as a prefix (e.g. temurin-21.0.2).
+func parseToolVersions(data []byte, source string) []discovery {
+	var found []discovery
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		tool, value := fields[0], fields[1]
+		candidates := []string{tool}
+		if tool == "java" {
+			distribution, version, _ := strings.Cut(value, "-")
+			candidates, value = []string{distribution}, version
+		}
+		if version := extractVersion(value); version != "" {
+			found = append(found, discovery{candidates: candidates, version: version, source: source})
+		}
+	}
+	return found
+}
+
+// parsePom reads the parent of a Maven project.
+func parsePom(data []byte, source string) []discovery {
+	var pom struct {
+		Parent struct {
+			GroupID    string `xml:"groupId"`
+			ArtifactID string `xml:"artifactId"`
+			Version    string `xml:"version"`
+		} `xml:"parent"`
+	}
+	if err := xml.Unmarshal(data, &pom); err != nil {
+		log.Warn().Err(err).Msgf("Skipping %s", source)
+		return nil
+	}
+	version := extractVersion(pom.Parent.Version)
+	if pom.Parent.ArtifactID == "" || version == "" || strings.HasPrefix(pom.Parent.Version, "$") {
+		return nil
+	}
+	groupName := pom.Parent.GroupID[strings.LastIndex(pom.Parent.GroupID, ".")+1:]
+	return []discovery{{candidates: []string{pom.Parent.ArtifactID, groupName}, version: version, source: source}}
+}
+
+// parseWorkflow reads the versions given to the setup-* actions of a GitHub workflow. Matrix
+// expressions cannot be resolved statically and are skipped.
+func parseWorkflow(data []byte, source string) []discovery {
+	var workflow struct {
+		Jobs map[string]struct {
+			Steps []struct {
+				Uses string         `yaml:"uses"`
+				With map[string]any `yaml:"with"`
+			} `yaml:"steps"`
+		} `yaml:"jobs"`
+	}
+	if err := yaml.Unmarshal(data, &workflow); err != nil {
+		log.Warn().Err(err).Msgf("Skipping %s", source)
+		return nil
+	}
+	var found []discovery
+	for _, job := range workflow.Jobs {
+		for _, step := range job.Steps {
+			action, _, _ := strings.Cut(step.Uses, "@")
+			setup, ok := setupActions[action]
+			if !ok {
+				continue
+			}
+			product := setup.product
+			if product == "" {
+				distribution, _ := step.With["distribution"].(string)
+				if distribution == "" {
+					distribution = "temurin"
+				}
+				product = distribution
+			}
+			for _, value := range yamlScalars(step.With[setup.input]) {
+				if strings.Contains(value, "${{") {
+					continue
+				}
+				if version := extractVersion(value); version != "" {
+					found = append(found, discovery{candidates: []string{product}, version: version, source: source})
+				}
+			}
+		}
+	}
+	return found
+}
+
+// yamlScalars returns the string form of a scalar, a list of scalars or a multi-line string.
+func yamlScalars(value any) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		var values []string
+		for _, item := range v {
+			values = append(values, yamlScalars(item)...)
+		}
+		return values
+	case string:
+		return strings.Fields(v)
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// parseCompose reads the image of every service of a docker-compose file.
+func parseCompose(data []byte, source string) []discovery {
+	var compose struct {
+		Services map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		log.Warn().Err(err).Msgf("Skipping %s", source)
+		return nil
+	}
+	var found []discovery
+	for _, service := range compose.Services {
+		if d, ok := imageDiscovery(service.Image, source); ok {
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+// resolveDiscoveries resolves the discoveries against the product cache, snaps their version to
+// an existing release cycle when possible, and removes duplicates. Names are unique: a product
+// found with several versions gets the version appended to its name.
+func resolveDiscoveries(found []discovery, products utilities.ProductsFile) []discoveredItem {
+	seen := map[string]bool{}
+	var items []discoveredItem
+	for _, d := range found {
+		prod, ok := "", false
+		for _, candidate := range d.candidates {
+			if prod, ok = resolveProductName(products, candidate); ok {
+				break
+			}
+			if alias, known := discoveryAliases[strings.ToLower(candidate)]; known {
+				if prod, ok = resolveProductName(products, alias); ok {
+					break
+				}
+			}
+		}
+		if !ok {
+			log.Warn().Msgf("%s: %s %s is not a known endoflife.date product, skipping it", d.source, d.candidates[0], d.version)
+			continue
+		}
+		version := d.version
+		if suggestion := findVersionSuggestion(prod, version); suggestion != "" {
+			version = suggestion
+		}
+		key := prod + "@" + version
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		items = append(items, discoveredItem{stackItem: stackItem{Name: prod, Version: version, IdEol: prod}, source: d.source})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].IdEol < items[j].IdEol })
+	count := map[string]int{}
+	for _, item := range items {
+		count[item.IdEol]++
+	}
+	for i := range items {
+		if count[items[i].IdEol] > 1 {
+			items[i].Name = items[i].IdEol + "-" + items[i].Version
+		}
+	}
+	return items
+}
+
+// loadOrCreateStackDocument parses the existing configuration file as a YAML node tree, keeping
+// comments and unknown fields, or builds a new document when the file does not exist.
+func loadOrCreateStackDocument(path, root, appName string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 && doc.Content[0].Kind == yaml.MappingNode {
+			log.Info().Msgf("Merging into the existing file %s", path)
+			return &doc, nil
+		}
+		return nil, fmt.Errorf("%s is not a YAML mapping", path)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if appName == "" {
+		if abs, err := filepath.Abs(root); err == nil {
+			appName = filepath.Base(abs)
+		}
+	}
+	config := struct {
+		GeolVersion string      `yaml:"geolVersion"`
+		AppName     string      `yaml:"app_name"`
+		AppID       string      `yaml:"app_id"`
+		Stack       []stackItem `yaml:"stack"`
+	}{GeolVersion: "2", AppName: appName, AppID: strings.ToLower(strings.ReplaceAll(appName, " ", "-")), Stack: []stackItem{}}
+	var doc yaml.Node
+	if err := doc.Encode(config); err != nil {
+		return nil, err
+	}
+	// The empty stack is encoded as [], switch it to block style for the appended items
+	if stack := mappingValue(&doc, "stack"); stack != nil {
+		stack.Style = 0
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&doc}}, nil
+}
+
+// mappingValue returns the value node of key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mergeDiscoveredItems merges the discovered items into the stack sequence of doc. An existing
+// item of the same product keeps all its fields, only its version is updated; it is chosen by
+// version first, then by name, then when it is the only item of that product. Other discovered
+// items are appended.
+func mergeDiscoveredItems(doc *yaml.Node, items []discoveredItem, products utilities.ProductsFile) error {
+	root := doc.Content[0]
+	stack := mappingValue(root, "stack")
+	if stack == nil || stack.Kind != yaml.SequenceNode {
+		stack = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "stack"}, stack)
+	}
+
+	byProduct := map[string][]*yaml.Node{}
+	for _, node := range stack.Content {
+		idEol := mappingValue(node, "id_eol")
+		if node.Kind != yaml.MappingNode || idEol == nil {
+			continue
+		}
+		prod, ok := resolveProductName(products, idEol.Value)
+		if !ok {
+			prod = idEol.Value
+		}
+		byProduct[prod] = append(byProduct[prod], node)
+	}
+	discoveredPerProduct := map[string]int{}
+	for _, item := range items {
+		discoveredPerProduct[item.IdEol]++
+	}
+
+	added, updated := 0, 0
+	for _, item := range items {
+		existing := byProduct[item.IdEol]
+		var match *yaml.Node
+		for _, node := range existing {
+			if v := mappingValue(node, "version"); v != nil && v.Value == item.Version {
+				match = node
+				break
+			}
+		}
+		if match != nil {
+			continue
+		}
+		for _, node := range existing {
+			if n := mappingValue(node, "name"); n != nil && n.Value == item.Name {
+				match = node
+				break
+			}
+		}
+		if match == nil && len(existing) == 1 && discoveredPerProduct[item.IdEol] == 1 {
+			match = existing[0]
+		}
+
+		if match != nil {
+			version := mappingValue(match, "version")
+			if version == nil {
+				continue
+			}
+			name := item.Name
+			if n := mappingValue(match, "name"); n != nil {
+				name = n.Value
+			}
+			log.Info().Msgf("Updated %s %s -> %s (%s)", name, version.Value, item.Version, item.source)
+			version.Value, version.Tag, version.Style = item.Version, "!!str", yaml.DoubleQuotedStyle
+			updated++
+			continue
+		}
+
+		var node yaml.Node
+		if err := node.Encode(item.stackItem); err != nil {
+			return err
+		}
+		if len(node.Content) > 1 {
+			node.Content[1].LineComment = "discovered in " + item.source
+		}
+		stack.Content = append(stack.Content, &node)
+		byProduct[item.IdEol] = append(byProduct[item.IdEol], &node)
+		log.Info().Msgf("Added %s %s (%s)", item.Name, item.Version, item.source)
+		added++
+	}
+	log.Info().Int("added", added).Int("updated", updated).Msg("Stack merged")
+	return nil
+}
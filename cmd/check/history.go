@@ -0,0 +1,382 @@
+package check
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"charm.land/lipgloss/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	CheckCmd.AddCommand(HistoryCmd)
+	HistoryCmd.Flags().StringP("app", "a", "", "Only show the history of this app (app_id, or app_name when unset)")
+	HistoryCmd.Flags().Bool("json", false, "Output in JSON format")
+}
+
+// HistoryCmd represents the check history command
+var HistoryCmd = &cobra.Command{
+	Use:     "history",
+	Aliases: []string{"hist"},
+	Short:   "Show the stack score trend and EOL history of the recorded checks.",
+	Long: `The 'history' command reads the runs recorded by 'geol check --record' and shows, for each app:
+- the trend of the stack debt score, run after run
+- when components entered EOL and when they left it (upgraded or removed)
+- how long each EOL component stayed unremediated, up to the last run when it is still EOL.`,
+	Example: `geol check --record
+geol check history
+geol check history --app my-api
+geol check history --json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		app, _ := cmd.Flags().GetString("app")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+
+		historyPath, err := utilities.GetHistoryPath()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error retrieving history path")
+		}
+		if _, err := os.Stat(historyPath); os.IsNotExist(err) {
+			log.Fatal().Msg("No history recorded yet, run 'geol check --record' first")
+		}
+		runs, err := loadHistory(historyPath, app)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("Error reading %s", historyPath)
+		}
+		if len(runs) == 0 {
+			log.Fatal().Msgf("No recorded run for app %q", app)
+		}
+		apps := buildAppHistories(runs)
+
+		if jsonOutput {
+			jsonData, err := json.MarshalIndent(apps, "", "  ")
+			if err != nil {
+				log.Fatal().Msg("Error generating JSON output: " + err.Error())
+			}
+			fmt.Println(string(jsonData))
+			return
+		}
+		for i, h := range apps {
+			if i > 0 {
+				fmt.Println()
+			}
+			renderAppHistory(h)
+		}
+	},
+}
+
+// historySchema creates the tables of the history store: one row per stack and run, and the
+// evaluated components of each of them.
+const historySchema = `
+CREATE TABLE IF NOT EXISTS runs(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	recorded_at TEXT NOT NULL,
+	reference_date TEXT NOT NULL,
+	app TEXT NOT NULL,
+	title TEXT NOT NULL,
+	file TEXT NOT NULL,
+	score INTEGER NOT NULL,
+	score_color TEXT NOT NULL,
+	score_message TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS components(
+	run_id INTEGER NOT NULL REFERENCES runs(id),
+	software TEXT NOT NULL,
+	version TEXT NOT NULL,
+	id_eol TEXT NOT NULL,
+	eol_date TEXT NOT NULL,
+	status TEXT NOT NULL,
+	is_latest INTEGER NOT NULL,
+	latest_version TEXT NOT NULL,
+	debt_score INTEGER NOT NULL,
+	weight REAL NOT NULL,
+	waived INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS runs_app ON runs(app, reference_date);
+CREATE INDEX IF NOT EXISTS components_run ON components(run_id);`
+
+// openHistory opens the history store, creating it when needed.
+func openHistory(historyPath string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", historyPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening SQLite database: %w", err)
+	}
+	if _, err := db.Exec(historySchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("error creating the history tables: %w", err)
+	}
+	return db, nil
+}
+
+// recordRuns appends the evaluated stacks to the history store, as of the today reference date.
+func recordRuns(results []stackResult, today time.Time) error {
+	historyPath, err := utilities.GetHistoryPath()
+	if err != nil {
+		return err
+	}
+	db, err := openHistory(historyPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Warn().Err(err).Msg("Error closing SQLite database")
+		}
+	}()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	recordedAt := time.Now().Format(time.RFC3339)
+	for _, r := range results {
+		res, err := tx.Exec(`INSERT INTO runs(recorded_at, reference_date, app, title, file, score, score_color, score_message)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			recordedAt, today.Format("2006-01-02"), r.appKey(), r.Title, r.File, r.Score.Value, r.Score.Color, r.Score.Message)
+		if err != nil {
+			return fmt.Errorf("error recording the run of %s: %w", r.appKey(), err)
+		}
+		runID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, row := range r.Rows {
+			if _, err := tx.Exec(`INSERT INTO components(run_id, software, version, id_eol, eol_date, status, is_latest, latest_version, debt_score, weight, waived)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				runID, row.Software, row.Version, row.IdEol, row.EolDate, row.Status, row.IsLatest, row.LatestVersion, row.DebtScore, row.Weight, row.Waived); err != nil {
+				return fmt.Errorf("error recording %s: %w", row.Software, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Info().Msgf("Recorded %d stack(s) in %s", len(results), historyPath)
+	return nil
+}
+
+// historyRun is a recorded check of a stack.
+type historyRun struct {
+	ID         int64
+	Date       string
+	App, Title string
+	Score      stackScore
+	Components []historyComponent
+}
+
+// historyComponent is a stack component, as evaluated by a recorded run.
+type historyComponent struct {
+	Software, Version, EolDate, Status string
+}
+
+// loadHistory returns the recorded runs of app, or of every app when app is empty, sorted by
+// reference date.
+func loadHistory(historyPath, app string) ([]historyRun, error) {
+	db, err := openHistory(historyPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Warn().Err(err).Msg("Error closing SQLite database")
+		}
+	}()
+
+	rows, err := db.Query(`SELECT id, reference_date, app, title, score, score_color, score_message FROM runs
+		WHERE ? = '' OR app = ? ORDER BY reference_date, id`, app, app)
+	if err != nil {
+		return nil, err
+	}
+	var runs []historyRun
+	byID := map[int64]int{}
+	for rows.Next() {
+		var run historyRun
+		if err := rows.Scan(&run.ID, &run.Date, &run.App, &run.Title, &run.Score.Value, &run.Score.Color, &run.Score.Message); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		byID[run.ID] = len(runs)
+		runs = append(runs, run)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	components, err := db.Query(`SELECT c.run_id, c.software, c.version, c.eol_date, c.status FROM components c
+		JOIN runs r ON r.id = c.run_id WHERE ? = '' OR r.app = ? ORDER BY c.run_id`, app, app)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = components.Close() }()
+	for components.Next() {
+		var runID int64
+		var c historyComponent
+		if err := components.Scan(&runID, &c.Software, &c.Version, &c.EolDate, &c.Status); err != nil {
+			return nil, err
+		}
+		if i, ok := byID[runID]; ok {
+			runs[i].Components = append(runs[i].Components, c)
+		}
+	}
+	return runs, components.Err()
+}
+
+// historyPoint is a point of the score trend of an app.
+type historyPoint struct {
+	Date          string `json:"date"`
+	Score         int    `json:"score"`
+	Color         string `json:"color"`
+	Components    int    `json:"components"`
+	EolComponents int    `json:"eol_components"`
+}
+
+// eolPeriod is a period during which a component of an app was past EOL.
+type eolPeriod struct {
+	Software string `json:"software"`
+	Version  string `json:"version"`
+	// Since is the EOL date of the component, or the date of the first run that saw it EOL
+	Since string `json:"since"`
+	// Until is the date of the first run where the component was no longer EOL, empty while it is
+	Until string `json:"until,omitempty"`
+	// Days is the number of days the component stayed unremediated, up to the last run while it is EOL
+	Days int `json:"days_unremediated"`
+}
+
+// appHistory is the history of an app: its score trend and its EOL periods.
+type appHistory struct {
+	App   string         `json:"app"`
+	Title string         `json:"title"`
+	Trend []historyPoint `json:"trend"`
+	Eol   []eolPeriod    `json:"eol"`
+}
+
+// buildAppHistories groups the runs by app, in the order apps were first recorded.
+func buildAppHistories(runs []historyRun) []appHistory {
+	var apps []appHistory
+	open := map[string]map[string]int{}
+	for _, run := range runs {
+		i := slices.IndexFunc(apps, func(h appHistory) bool { return h.App == run.App })
+		if i < 0 {
+			i = len(apps)
+			apps = append(apps, appHistory{App: run.App, Eol: []eolPeriod{}})
+			open[run.App] = map[string]int{}
+		}
+		h := &apps[i]
+		h.Title = run.Title
+
+		point := historyPoint{Date: run.Date, Score: run.Score.Value, Color: run.Score.Color, Components: len(run.Components)}
+		eol := map[string]historyComponent{}
+		for _, c := range run.Components {
+			if c.Status == "EOL" {
+				eol[c.Software] = c
+				point.EolComponents++
+			}
+		}
+		h.Trend = append(h.Trend, point)
+
+		// Close the periods of the components that are no longer EOL, then open the new ones
+		for software, p := range open[run.App] {
+			if _, ok := eol[software]; !ok {
+				h.Eol[p].Until = run.Date
+				h.Eol[p].Days = daysBetween(h.Eol[p].Since, run.Date)
+				delete(open[run.App], software)
+			}
+		}
+		for _, c := range run.Components {
+			if c.Status != "EOL" {
+				continue
+			}
+			if p, ok := open[run.App][c.Software]; ok {
+				h.Eol[p].Version = c.Version
+				h.Eol[p].Days = daysBetween(h.Eol[p].Since, run.Date)
+				continue
+			}
+			since := run.Date
+			if c.EolDate != "" && c.EolDate < since {
+				since = c.EolDate
+			}
+			open[run.App][c.Software] = len(h.Eol)
+			h.Eol = append(h.Eol, eolPeriod{Software: c.Software, Version: c.Version, Since: since, Days: daysBetween(since, run.Date)})
+		}
+	}
+	return apps
+}
+
+// daysBetween returns the number of days from one YYYY-MM-DD date to another.
+func daysBetween(from, to string) int {
+	f, errF := time.Parse("2006-01-02", from)
+	t, errT := time.Parse("2006-01-02", to)
+	if errF != nil || errT != nil {
+		return 0
+	}
+	return int(t.Sub(f).Hours() / 24)
+}
+
+// renderAppHistory prints the score trend and the EOL periods of an app.
+func renderAppHistory(h appHistory) {
+	title := h.Title
+	if h.App != h.Title {
+		title = fmt.Sprintf("%s (%s)", h.Title, h.App)
+	}
+	_, _ = lipgloss.Println(lipgloss.NewStyle().
+		Bold(true).Foreground(lipgloss.Color("#FFFF88")).
+		Background(lipgloss.Color("#5F5FFF")).
+		Render("## " + title))
+
+	first, last := h.Trend[0], h.Trend[len(h.Trend)-1]
+	if len(h.Trend) == 1 {
+		fmt.Printf("Stack Debt Score: %d/100, a single run on %s\n", last.Score, last.Date)
+	} else {
+		fmt.Printf("Stack Debt Score: %d/100 → %d/100 (%s) over %d runs, from %s to %s\n",
+			first.Score, last.Score, scoreDelta(last.Score-first.Score), len(h.Trend), first.Date, last.Date)
+	}
+
+	trend := newStackTable("Date", "Score", "Change", "Components", "EOL")
+	for i, p := range h.Trend {
+		change := "-"
+		if i > 0 {
+			change = scoreDelta(p.Score - h.Trend[i-1].Score)
+		}
+		trend.Row(p.Date, renderScoreBand(stackScore{Value: p.Score, Color: p.Color}), change, fmt.Sprint(p.Components), fmt.Sprint(p.EolComponents))
+	}
+	_, _ = lipgloss.Println(trend.Render())
+
+	if len(h.Eol) == 0 {
+		fmt.Println("No component was recorded past EOL.")
+		return
+	}
+	red := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	green := lipgloss.NewStyle().Foreground(lipgloss.Color("46"))
+	periods := newStackTable("Software", "Version", "EOL Since", "Left EOL", "Days Unremediated")
+	for _, p := range h.Eol {
+		until := red.Render("still EOL")
+		if p.Until != "" {
+			until = green.Render(p.Until)
+		}
+		periods.Row(p.Software, p.Version, p.Since, until, fmt.Sprint(p.Days))
+	}
+	_, _ = lipgloss.Println(periods.Render())
+}
+
+// scoreDelta formats a score change with its sign.
+func scoreDelta(delta int) string {
+	switch {
+	case delta > 0:
+		return fmt.Sprintf("+%d", delta)
+	case delta < 0:
+		return fmt.Sprint(delta)
+	default:
+		return "="
+	}
+}
@@ -0,0 +1,75 @@
+package check
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+)
+
+// alarmDays returns the --alarms flag value, the default reminders for commands without it. It
+// exits with an error when a reminder is not at least one day before its date.
+func alarmDays(cmd *cobra.Command) []int {
+	days, err := cmd.Flags().GetIntSlice("alarms")
+	if err != nil {
+		return utilities.DefaultAlarmDays
+	}
+	if err := utilities.ValidateAlarmDays(days); err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+	return days
+}
+
+// writeCalendar writes the EOL and end of active support dates of the stack components as an
+// iCalendar file, one event per date. The event UIDs are made of the app, the item and its
+// version, so that subscribed calendars update the events instead of duplicating them.
+func writeCalendar(w io.Writer, results []stackResult, today time.Time, alarms []int) error {
+	var events []utilities.CalendarEvent
+	var titles []string
+	for _, stack := range results {
+		titles = append(titles, stack.Title)
+		for _, row := range stack.Rows {
+			source := "endoflife.date"
+			if i := slices.IndexFunc(stack.Stack, func(item stackItem) bool { return item.Name == row.Software }); i >= 0 && stack.Stack[i].ManualEol != "" {
+				source = "manual_eol"
+			}
+			events = append(events, utilities.CalendarEvent{
+				UID:         utilities.CalendarUID(stack.appKey(), row.Software, row.Version, "eol"),
+				Date:        row.EolDate,
+				Summary:     fmt.Sprintf("EOL: %s %s (%s)", row.Software, row.Version, stack.Title),
+				Description: fmt.Sprintf("%s %s of %s reaches its end of life (source: %s).", row.Software, row.Version, stack.Title, source),
+			})
+			if row.IdEol == "" || source == "manual_eol" {
+				continue
+			}
+			if eoas := lookupEoasDate(row.IdEol, row.Version, today); eoas != "" {
+				events = append(events, utilities.CalendarEvent{
+					UID:         utilities.CalendarUID(stack.appKey(), row.Software, row.Version, "eoas"),
+					Date:        eoas,
+					Summary:     fmt.Sprintf("End of active support: %s %s (%s)", row.Software, row.Version, stack.Title),
+					Description: fmt.Sprintf("%s %s of %s reaches its end of active support, only security fixes follow until its EOL on %s.", row.Software, row.Version, stack.Title, valueOrDash(row.EolDate)),
+				})
+			}
+		}
+	}
+	return utilities.WriteICS(w, "geol: "+strings.Join(titles, ", "), events, alarms)
+}
+
+// lookupEoasDate returns the end of active support date of a version, empty when unknown.
+func lookupEoasDate(idEol, version string, today time.Time) string {
+	cycles, err := lookupReleaseCycles(idEol, today)
+	if err != nil {
+		return ""
+	}
+	for _, c := range cycles {
+		if c.Name == version {
+			return c.EoasFrom
+		}
+	}
+	return ""
+}
@@ -0,0 +1,457 @@
+package check
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	CheckCmd.AddCommand(ImageCmd)
+	ImageCmd.Flags().BoolP("strict", "s", false, "Exit with error if any product is EOL")
+	ImageCmd.Flags().Bool("json", false, "Output in JSON format (same as --format json)")
+	ImageCmd.Flags().String("format", "table", "Output format: "+strings.Join(outputFormats, ", "))
+	ImageCmd.Flags().StringP("date", "d", "", "Reference date for EOL calculations (format YYYY-MM-DD, default: today)")
+}
+
+// ImageCmd represents the check image command
+var ImageCmd = &cobra.Command{
+	Use:     "image <tarball>",
+	Aliases: []string{"img"},
+	Short:   "Check the base OS and runtimes of a container image tarball",
+	Long: `The image command reads a container image saved on disk, either as a docker archive ('docker save' output) or as an OCI image layout tarball, without any registry access.
+
+It detects the base OS from /etc/os-release and the main runtimes from the version files found in the layers:
+- Java: the release file of the JDK/JRE (JAVA_VERSION and IMPLEMENTOR)
+- Node.js: include/node/node_version.h
+- Python: the lib/pythonX.Y directories
+- Go: the VERSION file of the Go installation
+
+Detected products are mapped to endoflife.date ids and evaluated like the stack of 'geol check'.`,
+	Example: `docker save eclipse-temurin:21-jre -o image.tar
+geol check image image.tar
+geol check image image.tar --json --strict`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tarball := args[0]
+		if info, err := os.Stat(tarball); err != nil || info.IsDir() {
+			log.Fatal().Msgf("%s is not an image tarball. Only local tarballs are supported, export the image first with 'docker save <image> -o image.tar'", tarball)
+		}
+
+		img, err := readImageTarball(tarball)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("Error reading the image tarball %s", tarball)
+		}
+		found := img.discoveries()
+		if len(found) == 0 {
+			log.Fatal().Msgf("No OS or runtime detected in %s", tarball)
+		}
+
+		utilities.AnalyzeCacheProductsValidity(cmd)
+		productsPath, err := utilities.GetProductsPath()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error retrieving products path")
+		}
+		products, err := utilities.GetProductsWithCacheRefresh(cmd, productsPath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error retrieving products from cache")
+		}
+
+		items := resolveDiscoveries(found, products)
+		stack := make([]stackItem, 0, len(items))
+		for _, item := range items {
+			if !hasReleaseCycle(item.IdEol, item.Version) {
+				log.Warn().Msgf("%s: %s %s matches no release cycle, skipping it", item.source, item.IdEol, item.Version)
+				continue
+			}
+			log.Info().Msgf("Detected %s %s (%s)", item.IdEol, item.Version, item.source)
+			stack = append(stack, item.stackItem)
+		}
+		if len(stack) == 0 {
+			log.Fatal().Msgf("No OS or runtime with a known release cycle detected in %s", tarball)
+		}
+		reportStack(cmd, stackInput{Title: img.title, File: tarball, Stack: stack, Scoring: utilities.Scoring})
+	},
+}
+
+// imageFS holds the content of the image files used for detection, after applying the layers in
+// order, keyed by path without leading slash.
+type imageFS struct {
+	title string
+	files map[string][]byte
+}
+
+// layerFiles is what a single layer adds (files) and removes (whiteouts) among the files of interest.
+type layerFiles struct {
+	files     map[string][]byte
+	whiteouts []string
+	opaque    []string
+}
+
+var (
+	nodeVersionHeader = regexp.MustCompile(`(^|/)include/node/node_version\.h$`)
+	pythonLibDir      = regexp.MustCompile(`^(usr/(?:local/)?lib/python(\d+\.\d+))/`)
+	goVersionFile     = regexp.MustCompile(`(^|/)go/VERSION$`)
+	javaReleaseFile   = regexp.MustCompile(`(^|/)(jvm|java|jdk|jre|openjdk)[^/]*/(?:[^/]+/)?release$`)
+)
+
+// isFileOfInterest reports whether a layer file is used for detection. Python is detected from
+// directory names only, recorded as empty entries.
+func isFileOfInterest(name string) bool {
+	return name == "etc/os-release" || name == "usr/lib/os-release" ||
+		nodeVersionHeader.MatchString(name) || goVersionFile.MatchString(name) || javaReleaseFile.MatchString(name)
+}
+
+// maxDetectionFileSize bounds the size of the files kept in memory for detection.
+const maxDetectionFileSize = 64 << 10
+
+// maxMetadataSize bounds the size of the manifest, index and config JSON files of the archive.
+const maxMetadataSize = 16 << 20
+
+// readImageTarball reads a docker archive or OCI layout tarball in a single pass: JSON metadata
+// is kept in memory, layers are scanned as they come, then applied in manifest order.
+func readImageTarball(tarball string) (*imageFS, error) {
+	f, err := os.Open(tarball)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Debug().Err(err).Msg("Error closing the image tarball")
+		}
+	}()
+
+	metadata := map[string][]byte{}
+	layers := map[string]*layerFiles{}
+	archive := tar.NewReader(f)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(header.Name)
+		reader := bufio.NewReaderSize(archive, 1024)
+		if layer, ok, err := readLayer(reader); err != nil {
+			return nil, fmt.Errorf("error reading layer %s: %w", name, err)
+		} else if ok {
+			layers[name] = layer
+			continue
+		}
+		if header.Size <= maxMetadataSize {
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				return nil, err
+			}
+			metadata[name] = data
+		}
+	}
+
+	title, order, err := layerOrder(metadata)
+	if err != nil {
+		return nil, err
+	}
+	if title == "" {
+		title = filepath.Base(tarball)
+	}
+	img := &imageFS{title: title, files: map[string][]byte{}}
+	for _, name := range order {
+		layer, ok := layers[name]
+		if !ok {
+			return nil, fmt.Errorf("layer %s not found in the archive", name)
+		}
+		img.apply(layer)
+	}
+	return img, nil
+}
+
+// readLayer scans a layer when the reader holds a tar or gzipped tar stream, keeping the files
+// of interest. It returns false when the content is not a layer (e.g. a JSON file).
+func readLayer(reader *bufio.Reader) (*layerFiles, bool, error) {
+	head, _ := reader.Peek(512)
+	var stream io.Reader = reader
+	switch {
+	case len(head) >= 2 && head[0] == 0x1f && head[1] == 0x8b:
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, false, err
+		}
+		defer func() { _ = gz.Close() }()
+		stream = gz
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+	case len(head) == 512 && bytes.Count(head, []byte{0}) == len(head):
+		// Empty layers only hold the end-of-archive blocks
+		return &layerFiles{files: map[string][]byte{}}, true, nil
+	case len(head) >= 4 && bytes.Equal(head[:4], []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return nil, false, errors.New("zstd compressed layers are not supported")
+	default:
+		return nil, false, nil
+	}
+
+	layer := &layerFiles{files: map[string][]byte{}}
+	files := tar.NewReader(stream)
+	for {
+		header, err := files.Next()
+		if errors.Is(err, io.EOF) {
+			return layer, true, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		dir, base := path.Split(name)
+		if base == ".wh..wh..opq" {
+			layer.opaque = append(layer.opaque, dir)
+			continue
+		}
+		if removed, ok := strings.CutPrefix(base, ".wh."); ok {
+			layer.whiteouts = append(layer.whiteouts, dir+removed)
+			continue
+		}
+		if m := pythonLibDir.FindStringSubmatch(name + "/"); m != nil {
+			layer.files[m[1]] = nil
+		}
+		if header.Typeflag != tar.TypeReg || header.Size > maxDetectionFileSize || !isFileOfInterest(name) {
+			continue
+		}
+		data, err := io.ReadAll(files)
+		if err != nil {
+			return nil, false, err
+		}
+		layer.files[name] = data
+	}
+}
+
+// layerOrder returns the image title and the archive paths of its layers, lowest first, from a
+// docker archive manifest.json or an OCI index.json. Multi-platform OCI indexes use the
+// linux/amd64 manifest when present, the first one otherwise.
+func layerOrder(metadata map[string][]byte) (string, []string, error) {
+	if data, ok := metadata["manifest.json"]; ok {
+		var manifest []struct {
+			RepoTags []string `json:"RepoTags"`
+			Layers   []string `json:"Layers"`
+		}
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return "", nil, fmt.Errorf("invalid manifest.json: %w", err)
+		}
+		if len(manifest) == 0 {
+			return "", nil, errors.New("manifest.json lists no image")
+		}
+		title := ""
+		if len(manifest[0].RepoTags) > 0 {
+			title = manifest[0].RepoTags[0]
+		}
+		layers := make([]string, 0, len(manifest[0].Layers))
+		for _, layer := range manifest[0].Layers {
+			layers = append(layers, path.Clean(layer))
+		}
+		return title, layers, nil
+	}
+
+	data, ok := metadata["index.json"]
+	if !ok {
+		return "", nil, errors.New("neither manifest.json nor index.json found, not a docker archive or OCI layout")
+	}
+	type descriptor struct {
+		MediaType   string            `json:"mediaType"`
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+		Platform    *struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	}
+	title := ""
+	for range 4 { // an index may point to nested indexes
+		var doc struct {
+			Manifests []descriptor `json:"manifests"`
+			Layers    []descriptor `json:"layers"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return "", nil, fmt.Errorf("invalid OCI document: %w", err)
+		}
+		if len(doc.Layers) > 0 {
+			layers := make([]string, 0, len(doc.Layers))
+			for _, layer := range doc.Layers {
+				layers = append(layers, blobPath(layer.Digest))
+			}
+			return title, layers, nil
+		}
+		if len(doc.Manifests) == 0 {
+			return "", nil, errors.New("OCI index lists no manifest")
+		}
+		chosen := doc.Manifests[0]
+		for _, m := range doc.Manifests {
+			if m.Platform != nil && m.Platform.OS == "linux" && m.Platform.Architecture == "amd64" {
+				chosen = m
+				break
+			}
+		}
+		if ref := chosen.Annotations["org.opencontainers.image.ref.name"]; ref != "" && title == "" {
+			title = ref
+		}
+		if data, ok = metadata[blobPath(chosen.Digest)]; !ok {
+			return "", nil, fmt.Errorf("blob %s not found in the archive", chosen.Digest)
+		}
+	}
+	return "", nil, errors.New("OCI index nesting too deep")
+}
+
+// blobPath returns the OCI layout path of a digest (sha256:abc -> blobs/sha256/abc).
+func blobPath(digest string) string {
+	algorithm, hex, _ := strings.Cut(digest, ":")
+	return path.Join("blobs", algorithm, hex)
+}
+
+// apply applies a layer on top of the files: opaque directories and whiteouts first, then the
+// files the layer adds or replaces.
+func (img *imageFS) apply(layer *layerFiles) {
+	for name := range img.files {
+		for _, dir := range layer.opaque {
+			if strings.HasPrefix(name, dir) {
+				delete(img.files, name)
+			}
+		}
+		for _, removed := range layer.whiteouts {
+			if name == removed || strings.HasPrefix(name, removed+"/") {
+				delete(img.files, name)
+			}
+		}
+	}
+	for name, data := range layer.files {
+		img.files[name] = data
+	}
+}
+
+// osReleaseIDs maps /etc/os-release ID values to endoflife.date ids, when they differ.
+var osReleaseIDs = map[string]string{
+	"rocky":         "rocky-linux",
+	"amzn":          "amazon-linux",
+	"ol":            "oracle-linux",
+	"opensuse-leap": "opensuse",
+}
+
+// javaImplementors maps the IMPLEMENTOR of a JDK release file to endoflife.date ids.
+var javaImplementors = map[string]string{
+	"Eclipse Adoptium":   "eclipse-temurin",
+	"Amazon.com Inc.":    "amazon-corretto",
+	"Azul Systems, Inc.": "azul-zulu",
+	"BellSoft":           "bellsoft-liberica",
+	"Oracle Corporation": "oracle-jdk",
+	"Microsoft":          "microsoft-build-of-openjdk",
+	"IBM Corporation":    "ibm-semeru-runtime",
+	"Eclipse OpenJ9":     "ibm-semeru-runtime",
+}
+
+// parseEnvFile reads KEY=value lines, as found in os-release and JDK release files.
+func parseEnvFile(data []byte) map[string]string {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if ok {
+			values[key] = strings.Trim(value, `"'`)
+		}
+	}
+	return values
+}
+
+// discoveries returns the OS and runtimes detected in the image files.
+func (img *imageFS) discoveries() []discovery {
+	var found []discovery
+	names := make([]string, 0, len(img.files))
+	for name := range img.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	osRelease, ok := img.files["etc/os-release"]
+	if !ok {
+		osRelease, ok = img.files["usr/lib/os-release"]
+	}
+	if ok {
+		values := parseEnvFile(osRelease)
+		id := values["ID"]
+		if mapped, known := osReleaseIDs[id]; known {
+			id = mapped
+		}
+		if version := extractVersion(values["VERSION_ID"]); id != "" && version != "" {
+			found = append(found, discovery{candidates: []string{id}, version: version, source: "/etc/os-release"})
+		}
+	}
+
+	// Python: the interpreter of usr/local wins over the system one of usr/lib
+	var python discovery
+	for _, name := range names {
+		data := img.files[name]
+		switch {
+		case nodeVersionHeader.MatchString(name):
+			if version := nodeHeaderVersion(data); version != "" {
+				found = append(found, discovery{candidates: []string{"nodejs"}, version: version, source: "/" + name})
+			}
+		case goVersionFile.MatchString(name):
+			line, _, _ := strings.Cut(string(data), "\n")
+			if version := extractVersion(strings.TrimPrefix(line, "go")); version != "" {
+				found = append(found, discovery{candidates: []string{"go"}, version: version, source: "/" + name})
+			}
+		case javaReleaseFile.MatchString(name):
+			values := parseEnvFile(data)
+			version := extractVersion(values["JAVA_VERSION"])
+			if version == "" {
+				continue
+			}
+			// Java 8 reports 1.8.0_xxx
+			version = strings.TrimPrefix(version, "1.")
+			implementor := values["IMPLEMENTOR"]
+			candidates := []string{implementor}
+			if id, known := javaImplementors[implementor]; known {
+				candidates = []string{id}
+			}
+			found = append(found, discovery{candidates: candidates, version: version, source: "/" + name})
+		default:
+			if m := pythonLibDir.FindStringSubmatch(name + "/"); m != nil && (python.version == "" || strings.HasPrefix(name, "usr/local/")) {
+				python = discovery{candidates: []string{"python"}, version: m[2], source: "/" + m[1]}
+			}
+		}
+	}
+	if python.version != "" {
+		found = append(found, python)
+	}
+	return found
+}
+
+// nodeHeaderVersion reads NODE_MAJOR_VERSION and NODE_MINOR_VERSION from node_version.h.
+func nodeHeaderVersion(data []byte) string {
+	parts := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "#define" {
+			parts[fields[1]] = fields[2]
+		}
+	}
+	if parts["NODE_MAJOR_VERSION"] == "" {
+		return ""
+	}
+	return parts["NODE_MAJOR_VERSION"] + "." + parts["NODE_MINOR_VERSION"] + "." + parts["NODE_PATCH_VERSION"]
+}
@@ -0,0 +1,112 @@
+package check
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// writeJUnit writes the stacks as a JUnit XML report, one testsuite per stack and one testcase
+// per stack item: items with a violation that fails strict mode fail, items with a warning only
+// (e.g. nearing EOL, or not on the latest version) pass with the warning in their system-out, and
+// items marked with skip: true are skipped.
+func writeJUnit(w io.Writer, results []stackResult) error {
+	report := junitTestSuites{Name: "geol check"}
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	for _, stack := range results {
+		suite := junitSuite(stack)
+		suite.Timestamp = timestamp
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitSuite returns the testsuite of a stack.
+func junitSuite(stack stackResult) junitTestSuite {
+	byItem := map[string][]stackViolation{}
+	for _, v := range stack.Violations {
+		byItem[v.Item] = append(byItem[v.Item], v)
+	}
+
+	suite := junitTestSuite{Name: stack.Title}
+	for _, item := range stack.Stack {
+		testCase := junitTestCase{Name: item.Name, ClassName: stack.appKey()}
+		if item.Skip {
+			testCase.Skipped = &junitSkipped{Message: "skip: true"}
+			suite.Skipped++
+			suite.TestCases = append(suite.TestCases, testCase)
+			continue
+		}
+		var failures, warnings []string
+		var failureType string
+		for _, v := range byItem[item.Name] {
+			if !v.failsStrict() {
+				warnings = append(warnings, "WARN: "+v.Message)
+				continue
+			}
+			if failureType == "" {
+				failureType = v.RuleID()
+			}
+			failures = append(failures, v.Message)
+		}
+		if len(failures) > 0 {
+			testCase.Failure = &junitFailure{Message: failures[0], Type: failureType, Text: strings.Join(failures, "\n")}
+			suite.Failures++
+		}
+		testCase.SystemOut = strings.Join(warnings, "\n")
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	suite.Tests = len(suite.TestCases)
+	return suite
+}
@@ -0,0 +1,84 @@
+package check
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricFamily is a Prometheus gauge with its samples.
+type metricFamily struct {
+	name    string
+	help    string
+	samples []metricSample
+}
+
+// metricSample is a sample of a metricFamily, with its labels as name/value pairs.
+type metricSample struct {
+	labels []string
+	value  float64
+}
+
+// writeMetrics writes the results as Prometheus gauges, in the text exposition format.
+func writeMetrics(w io.Writer, results []stackResult, evaluated time.Time) error {
+	daysUntilEol := metricFamily{name: "geol_component_days_until_eol", help: "Days until the EOL date of the component, negative once past EOL."}
+	debtScore := metricFamily{name: "geol_component_debt_score", help: "Debt score of the component, from 0 (past EOL) to 100 (up to date)."}
+	isLatest := metricFamily{name: "geol_component_is_latest", help: "1 when the component is in the latest version of its product."}
+	ltsCompliant := metricFamily{name: "geol_component_lts_compliant", help: "1 when the component follows its lts_strategy, for components with one."}
+	stackScore := metricFamily{name: "geol_stack_score", help: "Debt score of the stack, from 0 to 100."}
+	lastEvaluation := metricFamily{name: "geol_last_evaluation_timestamp_seconds", help: "Unix time of the last evaluation of the stacks."}
+
+	for _, r := range results {
+		app := r.appKey()
+		stackScore.samples = append(stackScore.samples, metricSample{[]string{"app_id", app}, float64(r.Score.Value)})
+		for _, row := range r.Rows {
+			labels := []string{"app_id", app, "component", row.Software, "id_eol", row.IdEol, "version", row.Version}
+			if days, err := strconv.Atoi(row.Days); err == nil {
+				daysUntilEol.samples = append(daysUntilEol.samples, metricSample{labels, float64(days)})
+			}
+			debtScore.samples = append(debtScore.samples, metricSample{labels, float64(row.DebtScore)})
+			isLatest.samples = append(isLatest.samples, metricSample{labels, boolMetric(row.IsLatest)})
+			if row.LtsStrategy != "" {
+				broken := slices.ContainsFunc(r.Violations, func(v stackViolation) bool {
+					return v.Kind == violationLtsPolicy && v.Item == row.Software
+				})
+				ltsCompliant.samples = append(ltsCompliant.samples, metricSample{append(slices.Clone(labels), "lts_strategy", row.LtsStrategy), boolMetric(!broken)})
+			}
+		}
+	}
+	if !evaluated.IsZero() {
+		lastEvaluation.samples = append(lastEvaluation.samples, metricSample{nil, float64(evaluated.Unix())})
+	}
+
+	var b strings.Builder
+	for _, family := range []metricFamily{daysUntilEol, debtScore, isLatest, ltsCompliant, stackScore, lastEvaluation} {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", family.name, family.help, family.name)
+		for _, sample := range family.samples {
+			b.WriteString(family.name)
+			if len(sample.labels) > 0 {
+				pairs := make([]string, 0, len(sample.labels)/2)
+				for i := 0; i+1 < len(sample.labels); i += 2 {
+					pairs = append(pairs, sample.labels[i]+`="`+metricLabelEscaper.Replace(sample.labels[i+1])+`"`)
+				}
+				b.WriteString("{" + strings.Join(pairs, ",") + "}")
+			}
+			b.WriteString(" " + strconv.FormatFloat(sample.value, 'f', -1, 64) + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// boolMetric returns 1 for true and 0 for false.
+func boolMetric(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// metricLabelEscaper escapes a label value of the text exposition format.
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,339 @@
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
+)
+
+// notifyViolation is a violation in the summary sent to webhooks.
+type notifyViolation struct {
+	Rule     string `json:"rule"`
+	Item     string `json:"item"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// New is true when the violation was not reported by the previous run of the app
+	New bool `json:"new"`
+}
+
+// notifyApp is the summary of a stack sent to webhooks.
+type notifyApp struct {
+	App   string     `json:"app"`
+	Title string     `json:"title"`
+	File  string     `json:"file,omitempty"`
+	Score stackScore `json:"score"`
+	// PreviousScore is the score of the previous run of the app, nil on its first run
+	PreviousScore *int              `json:"previous_score,omitempty"`
+	ScoreChange   int               `json:"score_change"`
+	Components    int               `json:"components"`
+	Failed        bool              `json:"failed"`
+	Violations    []notifyViolation `json:"violations"`
+	NewViolations int               `json:"new_violations"`
+}
+
+// notifySummary is the payload of the json format, and the data of the template format.
+type notifySummary struct {
+	Tool          string      `json:"tool"`
+	Version       string      `json:"version"`
+	ReferenceDate string      `json:"reference_date"`
+	Score         stackScore  `json:"score"`
+	Failed        bool        `json:"failed"`
+	Apps          []notifyApp `json:"apps"`
+}
+
+// notifyState is the score and violations of the previous run of every app, to detect new
+// violations and score drops.
+type notifyState map[string]notifyAppState
+
+// notifyAppState is the score and violations of the previous run of an app.
+type notifyAppState struct {
+	Score      int      `json:"score"`
+	Violations []string `json:"violations"`
+}
+
+// notifyStatePath returns the path to the file holding the notifyState.
+func notifyStatePath() (string, error) {
+	geolDir, err := utilities.GetGeolDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(geolDir, "notify-state.json"), nil
+}
+
+// violationKey identifies a violation across runs.
+func violationKey(v stackViolation) string {
+	return v.RuleID() + "|" + v.Item
+}
+
+// notifyResults posts a summary of the stacks to the webhooks of the configuration file and of
+// the stack files whose condition is met. Delivery failures are logged, they do not change the
+// result of the check.
+func notifyResults(results []stackResult, combined stackScore, today time.Time) {
+	type notification struct {
+		target utilities.NotifyTarget
+		apps   []int
+	}
+	var notifications []notification
+	for _, target := range utilities.Notify {
+		all := make([]int, len(results))
+		for i := range results {
+			all[i] = i
+		}
+		notifications = append(notifications, notification{target, all})
+	}
+	for i, r := range results {
+		for _, target := range r.Notify {
+			j := slices.IndexFunc(notifications, func(n notification) bool { return notifyTargetsEqual(n.target, target) })
+			if j < 0 {
+				notifications = append(notifications, notification{target: target})
+				j = len(notifications) - 1
+			}
+			if !slices.Contains(notifications[j].apps, i) {
+				notifications[j].apps = append(notifications[j].apps, i)
+			}
+		}
+	}
+	if len(notifications) == 0 {
+		return
+	}
+
+	statePath, err := notifyStatePath()
+	if err != nil {
+		log.Error().Err(err).Msg("Error retrieving the notification state path")
+		return
+	}
+	previous := notifyState{}
+	if data, err := os.ReadFile(statePath); err == nil {
+		if err := json.Unmarshal(data, &previous); err != nil {
+			log.Warn().Err(err).Msgf("Ignoring the invalid notification state %s", statePath)
+		}
+	}
+
+	apps := make([]notifyApp, len(results))
+	for i, r := range results {
+		apps[i] = summarizeApp(r, previous)
+	}
+
+	// undelivered are the apps of the notifications that failed, whose state is kept so that their
+	// new violations and score drops are notified again on the next run.
+	undelivered := map[int]bool{}
+	for _, n := range notifications {
+		summary := notifySummary{Tool: "geol", Version: utilities.Version, ReferenceDate: today.Format("2006-01-02"), Score: combined}
+		for _, i := range n.apps {
+			summary.Apps = append(summary.Apps, apps[i])
+			summary.Failed = summary.Failed || apps[i].Failed
+		}
+		if len(n.apps) < len(results) {
+			var rows []stackTableRow
+			for _, i := range n.apps {
+				rows = append(rows, results[i].Rows...)
+			}
+			summary.Score = computeStackScore(rows, notifyBands(results, n.apps))
+		}
+		if !notifyConditionMet(n.target, summary.Apps) {
+			log.Debug().Msgf("Notification condition %q not met for %s", n.target.On, webhookHost(n.target))
+			continue
+		}
+		if err := sendNotification(n.target, summary); err != nil {
+			log.Error().Err(err).Msgf("Error notifying %s", webhookHost(n.target))
+			for _, i := range n.apps {
+				undelivered[i] = true
+			}
+			continue
+		}
+		log.Info().Msgf("Notified %s", webhookHost(n.target))
+	}
+
+	next := notifyState{}
+	for key, state := range previous {
+		next[key] = state
+	}
+	for i, r := range results {
+		if undelivered[i] {
+			continue
+		}
+		state := notifyAppState{Score: r.Score.Value}
+		for _, v := range r.Violations {
+			state.Violations = append(state.Violations, violationKey(v))
+		}
+		next[r.appKey()] = state
+	}
+	data, err := json.MarshalIndent(next, "", "  ")
+	if err == nil {
+		err = os.WriteFile(statePath, data, 0o644)
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("Error writing the notification state %s", statePath)
+	}
+}
+
+// notifyBands returns the score bands of the apps of a notification: the bands of their scoring
+// model when they share one, the bands of the configuration file otherwise, as for the combined score.
+func notifyBands(results []stackResult, apps []int) utilities.ScoringBands {
+	bands := results[apps[0]].Scoring.Bands
+	for _, i := range apps[1:] {
+		if results[i].Scoring.Bands != bands {
+			return utilities.Scoring.Bands
+		}
+	}
+	return bands
+}
+
+// notifyTargetsEqual returns true when two targets post the same notification to the same URL.
+func notifyTargetsEqual(a, b utilities.NotifyTarget) bool {
+	return a.URL == b.URL && a.Format == b.Format && a.Template == b.Template && a.On == b.On && a.MinScoreDrop == b.MinScoreDrop
+}
+
+// summarizeApp returns the summary of a stack, compared to its previous run.
+func summarizeApp(r stackResult, previous notifyState) notifyApp {
+	app := notifyApp{
+		App: r.appKey(), Title: r.Title, File: r.File, Score: r.Score,
+		Components: len(r.Rows), Failed: r.ErrorOut, Violations: []notifyViolation{},
+	}
+	prev, seen := previous[r.appKey()]
+	if seen {
+		app.PreviousScore = &prev.Score
+		app.ScoreChange = r.Score.Value - prev.Score
+	}
+	for _, v := range r.Violations {
+		severity := "error"
+		if v.Warning {
+			severity = "warning"
+		}
+		isNew := !slices.Contains(prev.Violations, violationKey(v))
+		if isNew {
+			app.NewViolations++
+		}
+		app.Violations = append(app.Violations, notifyViolation{Rule: v.RuleID(), Item: v.Item, Severity: severity, Message: v.Message, New: isNew})
+	}
+	return app
+}
+
+// notifyConditionMet returns true when the condition of the target is met by one of the apps.
+func notifyConditionMet(target utilities.NotifyTarget, apps []notifyApp) bool {
+	minDrop := max(target.MinScoreDrop, 1)
+	return slices.ContainsFunc(apps, func(app notifyApp) bool {
+		switch target.On {
+		case "violations":
+			return len(app.Violations) > 0
+		case "new-violations":
+			return app.NewViolations > 0
+		case "score-drop":
+			return app.PreviousScore != nil && -app.ScoreChange >= minDrop
+		default:
+			return true
+		}
+	})
+}
+
+// sendNotification posts the summary to the webhook, in the format of the target.
+func sendNotification(target utilities.NotifyTarget, summary notifySummary) error {
+	var body []byte
+	var err error
+	switch target.Format {
+	case "slack":
+		body, err = json.Marshal(map[string]string{"text": summaryText(summary, "*")})
+	case "teams":
+		body, err = json.Marshal(teamsMessage(summary))
+	case "template":
+		var buf bytes.Buffer
+		tmpl, parseErr := template.New("notify").Parse(target.Template)
+		if parseErr != nil {
+			return parseErr
+		}
+		err = tmpl.Execute(&buf, summary)
+		body = buf.Bytes()
+	default:
+		body, err = json.Marshal(summary)
+	}
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target.ExpandedURL(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "geol/"+utilities.Version)
+	for name, value := range target.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+	resp, err := utilities.HTTPDoWithoutReplay(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// summaryText returns the summary as a short message, with bold marked by the bold delimiter.
+func summaryText(summary notifySummary, bold string) string {
+	var b strings.Builder
+	status := "passed"
+	if summary.Failed {
+		status = "failed"
+	}
+	fmt.Fprintf(&b, "%sgeol check %s%s: stack debt score %d/100 (%s)\n", bold, status, bold, summary.Score.Value, summary.ReferenceDate)
+	for _, app := range summary.Apps {
+		change := ""
+		if app.PreviousScore != nil {
+			change = fmt.Sprintf(" (%s)", scoreDelta(app.ScoreChange))
+		}
+		fmt.Fprintf(&b, "%s%s%s: %d/100%s, %d violation(s), %d new\n", bold, app.Title, bold, app.Score.Value, change, len(app.Violations), app.NewViolations)
+		for _, v := range app.Violations {
+			marker := "•"
+			if v.New {
+				marker = "• NEW"
+			}
+			fmt.Fprintf(&b, "%s [%s] %s\n", marker, v.Severity, v.Message)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// teamsMessage returns the summary as a Microsoft Teams message with an Adaptive Card, the
+// format of Teams workflow webhooks.
+func teamsMessage(summary notifySummary) map[string]any {
+	lines := strings.Split(summaryText(summary, "**"), "\n")
+	body := []map[string]any{{"type": "TextBlock", "text": lines[0], "size": "Medium", "weight": "Bolder", "wrap": true}}
+	for _, line := range lines[1:] {
+		body = append(body, map[string]any{"type": "TextBlock", "text": line, "wrap": true, "spacing": "None"})
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
+
+// webhookHost returns the host of the webhook, to log it without its secret path or token.
+func webhookHost(target utilities.NotifyTarget) string {
+	if u, err := url.Parse(target.ExpandedURL()); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "webhook"
+}
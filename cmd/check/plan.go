@@ -0,0 +1,372 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"charm.land/lipgloss/v2"
+	"github.com/Masterminds/semver/v3"
+	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	CheckCmd.AddCommand(PlanCmd)
+	PlanCmd.Flags().StringP("file", "f", ".geol.yaml", "Stack file to plan the upgrades of")
+	PlanCmd.Flags().String("format", "table", "Output format: table, markdown, json")
+	PlanCmd.Flags().StringP("env", "e", "", "Environment whose overlay is applied to the stack (see environments in the stack file)")
+	PlanCmd.Flags().StringP("date", "d", "", "Reference date for EOL calculations (format YYYY-MM-DD, default: today)")
+}
+
+// PlanCmd represents the check plan command
+var PlanCmd = &cobra.Command{
+	Use:     "plan",
+	Aliases: []string{"p"},
+	Short:   "Recommend a target version for each component, ordered by urgency.",
+	Long: `The 'plan' command recommends a target release cycle for each component of the stack, following its policy:
+- lts_strategy: the latest active LTS cycle ('any' keeps the current cycle while it is an active LTS)
+- always-latest: the newest cycle
+- otherwise: the supported cycle with the longest runway, i.e. the latest EOL date
+The upgrades are ordered by urgency, the closest deadline (the EOL date of the current cycle) first, with the number of major versions jumped.`,
+	Example: `geol check plan
+geol check plan --file stack.yaml --format markdown > UPGRADES.md
+geol check plan --date 2027-01-01`,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		format, _ := cmd.Flags().GetString("format")
+		env, _ := cmd.Flags().GetString("env")
+		if format != "table" && format != "markdown" && format != "json" {
+			log.Fatal().Msgf("Unknown output format %q: expected table, markdown or json", format)
+		}
+		input := readStackFile(file, env)
+
+		utilities.AnalyzeCacheProductsValidity(cmd)
+		today := referenceDate(cmd)
+		plan := planUpgrades(input, today)
+
+		var err error
+		switch format {
+		case "json":
+			var jsonData []byte
+			jsonData, err = json.MarshalIndent(plan, "", "  ")
+			if err == nil {
+				fmt.Println(string(jsonData))
+			}
+		case "markdown":
+			err = writePlanMarkdown(os.Stdout, plan)
+		default:
+			renderPlanTable(plan, input.Scoring.RiskThresholdDays)
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error generating the upgrade plan")
+		}
+	},
+}
+
+// Strategies used to choose the target of a component.
+const (
+	targetLatestLts     = "latest LTS"
+	targetActiveLts     = "active LTS"
+	targetNewest        = "newest cycle"
+	targetLongestRunway = "longest runway"
+)
+
+// releaseCycle is a release cycle of a product, as returned by endoflife.date.
+type releaseCycle struct {
+	Name        string `json:"name"`
+	ReleaseDate string `json:"releaseDate"`
+	EoasFrom    string `json:"eoasFrom"`
+	EolFrom     string `json:"eolFrom"`
+	IsLts       bool   `json:"isLts"`
+}
+
+// supportedOn returns true when the cycle has not reached its EOL date on the today reference date.
+func (c releaseCycle) supportedOn(today time.Time) bool {
+	eol, err := time.Parse("2006-01-02", c.EolFrom)
+	return err != nil || !eol.Before(today)
+}
+
+// lookupReleaseCycles returns the release cycles of an id_eol released as of the today reference
+// date, newest first.
+func lookupReleaseCycles(idEol string, today time.Time) ([]releaseCycle, error) {
+	productsPath, err := utilities.GetProductsPath()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving products path: %w", err)
+	}
+	products, err := utilities.GetProductsWithCacheRefresh(nil, productsPath)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving products from cache: %w", err)
+	}
+	prod, found := resolveProductName(products, idEol)
+	if !found {
+		return nil, fmt.Errorf("product with id_eol %s not found in the API", idEol)
+	}
+	body, err := fetchProductBody(prod)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting %s: %w", prod, err)
+	}
+	var payload struct {
+		Result struct {
+			Releases []releaseCycle `json:"releases"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("error decoding JSON for %s: %w", prod, err)
+	}
+	var cycles []releaseCycle
+	for _, c := range payload.Result.Releases {
+		if released, err := time.Parse("2006-01-02", c.ReleaseDate); err == nil && released.After(today) {
+			continue
+		}
+		cycles = append(cycles, c)
+	}
+	return cycles, nil
+}
+
+// upgradeStep is the recommended target of a stack item.
+type upgradeStep struct {
+	// Priority is the rank of the upgrade by urgency, 0 when the item is already on its target
+	Priority int    `json:"priority,omitempty"`
+	Software string `json:"software"`
+	Current  string `json:"current"`
+	Target   string `json:"target,omitempty"`
+	Strategy string `json:"strategy,omitempty"`
+	// Deadline is the EOL date of the current cycle
+	Deadline string `json:"deadline,omitempty"`
+	// DaysLeft is the number of days before the deadline, negative once past
+	DaysLeft     *int   `json:"days_left,omitempty"`
+	MajorsJumped int    `json:"majors_jumped"`
+	TargetEol    string `json:"target_eol,omitempty"`
+	Note         string `json:"note,omitempty"`
+}
+
+// upgradePlan is the upgrade plan of a stack.
+type upgradePlan struct {
+	Title         string        `json:"title"`
+	ReferenceDate string        `json:"reference_date"`
+	Steps         []upgradeStep `json:"steps"`
+}
+
+// planUpgrades recommends a target for every item of the stack, and orders the upgrades by urgency.
+func planUpgrades(input stackInput, today time.Time) upgradePlan {
+	prefetchProductBodies(input.Stack)
+	plan := upgradePlan{Title: input.Title, ReferenceDate: today.Format("2006-01-02"), Steps: []upgradeStep{}}
+	for _, item := range input.Stack {
+		if item.Skip {
+			continue
+		}
+		plan.Steps = append(plan.Steps, planItem(item, today))
+	}
+
+	needsUpgrade := func(s upgradeStep) bool { return s.Target != "" && s.Target != s.Current }
+	sort.SliceStable(plan.Steps, func(i, j int) bool {
+		a, b := plan.Steps[i], plan.Steps[j]
+		if needsUpgrade(a) != needsUpgrade(b) {
+			return needsUpgrade(a)
+		}
+		if (a.DaysLeft == nil) != (b.DaysLeft == nil) {
+			return a.DaysLeft != nil
+		}
+		if a.DaysLeft != nil && *a.DaysLeft != *b.DaysLeft {
+			return *a.DaysLeft < *b.DaysLeft
+		}
+		return a.Software < b.Software
+	})
+	for i := range plan.Steps {
+		if needsUpgrade(plan.Steps[i]) {
+			plan.Steps[i].Priority = i + 1
+		}
+	}
+	return plan
+}
+
+// planItem chooses the target cycle of a stack item, following its lts_strategy or always-latest
+// policy, or the longest runway otherwise.
+func planItem(item stackItem, today time.Time) upgradeStep {
+	step := upgradeStep{Software: item.Name, Current: item.Version}
+	if item.ManualEol != "" {
+		step.Deadline = item.ManualEol
+		step.DaysLeft = daysLeft(item.ManualEol, today)
+		step.Note = "manual_eol: no release data to choose a target from"
+		return step
+	}
+	cycles, err := lookupReleaseCycles(item.IdEol, today)
+	if err != nil {
+		log.Warn().Msgf("%s: %v", item.Name, err)
+		step.Note = "no release data"
+		return step
+	}
+	currentIndex := slices.IndexFunc(cycles, func(c releaseCycle) bool { return c.Name == item.Version })
+	if currentIndex < 0 {
+		step.Note = fmt.Sprintf("version %s not found in the release cycles", item.Version)
+		return step
+	}
+	current := &cycles[currentIndex]
+	step.Deadline = current.EolFrom
+	step.DaysLeft = daysLeft(current.EolFrom, today)
+
+	var target *releaseCycle
+	switch {
+	case item.LtsStrategy == "any" && current.IsLts && current.supportedOn(today):
+		step.Strategy, target = targetActiveLts, current
+	case item.LtsStrategy != "":
+		step.Strategy = targetLatestLts
+		for i := range cycles {
+			if cycles[i].IsLts && cycles[i].supportedOn(today) {
+				target = &cycles[i]
+				break
+			}
+		}
+		if target == nil {
+			step.Note = "no active LTS cycle"
+		}
+	case item.ShouldAlwaysBeLatest:
+		step.Strategy, target = targetNewest, &cycles[0]
+	default:
+		step.Strategy = targetLongestRunway
+		target = longestRunway(cycles, currentIndex, today)
+	}
+	if target == nil {
+		return step
+	}
+	step.Target = target.Name
+	step.TargetEol = target.EolFrom
+	step.MajorsJumped = majorsJumped(current.Name, target.Name, cycles)
+	if target.Name == current.Name {
+		step.Note = "already on target"
+	}
+	return step
+}
+
+// longestRunway returns the supported cycle, among the current cycle at index current and the
+// newer ones, with the latest EOL date. A cycle without EOL date has the longest runway, and ties
+// go to the smallest upgrade.
+func longestRunway(cycles []releaseCycle, current int, today time.Time) *releaseCycle {
+	var best *releaseCycle
+	// Cycles are newest first: walk them from current to the newest
+	for i := current; i >= 0; i-- {
+		if !cycles[i].supportedOn(today) {
+			continue
+		}
+		if best == nil || runwayLonger(cycles[i].EolFrom, best.EolFrom) {
+			best = &cycles[i]
+		}
+	}
+	return best
+}
+
+// runwayLonger returns true when EOL date a is strictly later than b, no date being the latest.
+func runwayLonger(a, b string) bool {
+	switch {
+	case b == "":
+		return false
+	case a == "":
+		return true
+	default:
+		return a > b
+	}
+}
+
+// majorsJumped returns the number of distinct major versions from the cycle from (excluded) to the
+// cycle to (included), 0 when the versions have no numeric major.
+func majorsJumped(from, to string, cycles []releaseCycle) int {
+	vf, errF := semver.NewVersion(from)
+	vt, errT := semver.NewVersion(to)
+	if errF != nil || errT != nil {
+		return 0
+	}
+	majors := map[uint64]bool{}
+	for _, c := range cycles {
+		if v, err := semver.NewVersion(c.Name); err == nil && v.Major() > vf.Major() && v.Major() <= vt.Major() {
+			majors[v.Major()] = true
+		}
+	}
+	return len(majors)
+}
+
+// daysLeft returns the number of days from the today reference date to an EOL date, nil when the
+// date is not known.
+func daysLeft(eolDate string, today time.Time) *int {
+	eol, err := time.Parse("2006-01-02", eolDate)
+	if err != nil {
+		return nil
+	}
+	days := int(eol.Sub(today).Hours() / 24)
+	return &days
+}
+
+// deadlineText formats the deadline of a step, with the days left or elapsed.
+func (s upgradeStep) deadlineText() string {
+	switch {
+	case s.DaysLeft == nil:
+		return "-"
+	case *s.DaysLeft < 0:
+		return fmt.Sprintf("%s (EOL %dd ago)", s.Deadline, -*s.DaysLeft)
+	default:
+		return fmt.Sprintf("%s (in %dd)", s.Deadline, *s.DaysLeft)
+	}
+}
+
+// renderPlanTable prints the upgrade plan as a table, the deadlines within riskThresholdDays (see the
+// scoring model of the stack) in orange.
+func renderPlanTable(plan upgradePlan, riskThresholdDays int) {
+	_, _ = lipgloss.Println(lipgloss.NewStyle().
+		Bold(true).Foreground(lipgloss.Color("#FFFF88")).
+		Background(lipgloss.Color("#5F5FFF")).
+		Render("## Upgrade plan: " + plan.Title))
+	red := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	orange := lipgloss.NewStyle().Foreground(lipgloss.Color("208"))
+	t := newStackTable("#", "Software", "Current", "Target", "Strategy", "Deadline", "Majors", "Target EOL", "Note")
+	for _, s := range plan.Steps {
+		priority, deadline := "-", s.deadlineText()
+		if s.Priority > 0 {
+			priority = fmt.Sprint(s.Priority)
+		}
+		switch {
+		case s.DaysLeft != nil && *s.DaysLeft < 0:
+			deadline = red.Render(deadline)
+		case s.DaysLeft != nil && *s.DaysLeft < riskThresholdDays:
+			deadline = orange.Render(deadline)
+		}
+		t.Row(priority, s.Software, s.Current, valueOrDash(s.Target), valueOrDash(s.Strategy), deadline, fmt.Sprint(s.MajorsJumped), valueOrDash(s.TargetEol), s.Note)
+	}
+	_, _ = lipgloss.Println(t.Render())
+}
+
+// writePlanMarkdown writes the upgrade plan as a markdown checklist, by urgency.
+func writePlanMarkdown(w io.Writer, plan upgradePlan) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### Upgrade plan: %s\n\n", plan.Title)
+	fmt.Fprintf(&b, "As of %s, by urgency:\n\n", plan.ReferenceDate)
+	for _, s := range plan.Steps {
+		switch {
+		case s.Priority > 0:
+			fmt.Fprintf(&b, "- [ ] **%s %s → %s** (%s): deadline %s, %d major version(s) jumped", s.Software, s.Current, s.Target, s.Strategy, s.deadlineText(), s.MajorsJumped)
+			if s.TargetEol != "" {
+				fmt.Fprintf(&b, ", supported until %s", s.TargetEol)
+			}
+			b.WriteString("\n")
+		case s.Target != "":
+			fmt.Fprintf(&b, "- [x] %s %s: already on target (%s)\n", s.Software, s.Current, s.Strategy)
+		default:
+			fmt.Fprintf(&b, "- [ ] %s %s: %s\n", s.Software, s.Current, s.Note)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// valueOrDash returns value, or "-" when it is empty.
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,212 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
+)
+
+// violationPolicy is the kind of the violations of the policies: section, whose SARIF rule id is
+// "policy/<policy id>".
+const violationPolicy = "policy"
+
+// policyRule is a rule of the policies: section of a stack file. The selectors (category, tag,
+// id_eol) choose the stack items it applies to, all items when none is set, and the conditions
+// (min_days_to_eol, max_majors_behind, require_lts) are checked on each of them.
+type policyRule struct {
+	ID          string `yaml:"id"`
+	Description string `yaml:"description,omitempty"`
+	// Severity is "error", which fails the check in strict mode, or "warning"
+	Severity        string   `yaml:"severity"`
+	Category        string   `yaml:"category,omitempty"`
+	Tag             string   `yaml:"tag,omitempty"`
+	IdEol           []string `yaml:"id_eol,omitempty"`
+	MinDaysToEol    *int     `yaml:"min_days_to_eol,omitempty"`
+	MaxMajorsBehind *int     `yaml:"max_majors_behind,omitempty"`
+	RequireLts      bool     `yaml:"require_lts,omitempty"`
+}
+
+// RuleID returns the rule id of a violation: its kind, or "policy/<policy id>" for policy violations.
+func (v stackViolation) RuleID() string {
+	if v.Kind == violationPolicy {
+		return violationPolicy + "/" + v.Rule
+	}
+	return v.Kind
+}
+
+// checkPolicies returns the errors of the policies: section.
+func checkPolicies(policies []policyRule) []string {
+	var errs []string
+	seen := map[string]bool{}
+	for i, p := range policies {
+		if p.ID == "" {
+			errs = append(errs, fmt.Sprintf("policies[%d].id is required", i))
+		} else if seen[p.ID] {
+			errs = append(errs, fmt.Sprintf("duplicate policy id '%s'", p.ID))
+		}
+		seen[p.ID] = true
+		if p.Severity != "error" && p.Severity != "warning" {
+			errs = append(errs, fmt.Sprintf("policies[%d].severity must be 'error' or 'warning', got '%s'", i, p.Severity))
+		}
+		if p.MinDaysToEol == nil && p.MaxMajorsBehind == nil && !p.RequireLts {
+			errs = append(errs, fmt.Sprintf("policies[%d] needs a condition: min_days_to_eol, max_majors_behind or require_lts", i))
+		}
+		if p.MaxMajorsBehind != nil && *p.MaxMajorsBehind < 0 {
+			errs = append(errs, fmt.Sprintf("policies[%d].max_majors_behind must be >= 0, got %d", i, *p.MaxMajorsBehind))
+		}
+	}
+	return errs
+}
+
+// productMetadata is the part of a products/{name} payload that policies select and check on.
+type productMetadata struct {
+	Category string
+	Tags     []string
+	// Releases are the release cycle names, newest first
+	Releases []string
+}
+
+// lookupProductMetadata returns the category, tags and release cycles of an id_eol.
+func lookupProductMetadata(idEol string) (productMetadata, error) {
+	body, err := fetchProductBody(canonicalProductName(idEol))
+	if err != nil {
+		return productMetadata{}, err
+	}
+	var payload struct {
+		Result struct {
+			Category string   `json:"category"`
+			Tags     []string `json:"tags"`
+			Releases []struct {
+				Name string `json:"name"`
+			} `json:"releases"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return productMetadata{}, err
+	}
+	metadata := productMetadata{Category: payload.Result.Category, Tags: payload.Result.Tags}
+	for _, r := range payload.Result.Releases {
+		metadata.Releases = append(metadata.Releases, r.Name)
+	}
+	return metadata, nil
+}
+
+// loadProductsFile returns the products and aliases of the local cache.
+func loadProductsFile() (utilities.ProductsFile, error) {
+	productsPath, err := utilities.GetProductsPath()
+	if err != nil {
+		return utilities.ProductsFile{}, err
+	}
+	return utilities.GetProductsWithCacheRefresh(nil, productsPath)
+}
+
+// majorsBehind returns the number of distinct major versions released after the major version of
+// version, and false when the version has no numeric major.
+func majorsBehind(version string, releases []string) (int, bool) {
+	current, err := semver.NewVersion(version)
+	if err != nil {
+		return 0, false
+	}
+	newer := map[uint64]bool{}
+	for _, name := range releases {
+		if v, err := semver.NewVersion(name); err == nil && v.Major() > current.Major() {
+			newer[v.Major()] = true
+		}
+	}
+	return len(newer), true
+}
+
+// evaluatePolicies checks the policies on the evaluated rows, and returns their violations: the
+// broken rules of severity warning are warnings.
+func evaluatePolicies(policies []policyRule, rows []stackTableRow) []stackViolation {
+	var violations []stackViolation
+	if len(policies) == 0 {
+		return nil
+	}
+	products, productsErr := loadProductsFile()
+	for _, row := range rows {
+		// Rows that are not endoflife.date products, i.e. manual_eol items, have no metadata: the
+		// metadata selectors do not select them.
+		var metadata productMetadata
+		var metadataErr error
+		switch {
+		case row.IdEol == "":
+		case productsErr != nil:
+			metadataErr = productsErr
+		default:
+			if _, found := resolveProductName(products, row.IdEol); found {
+				metadata, metadataErr = lookupProductMetadata(row.IdEol)
+			}
+		}
+		for _, p := range policies {
+			if metadataErr != nil && p.needsMetadata() {
+				// Without the product metadata the rule cannot be evaluated: report it rather than
+				// letting the check pass silently.
+				if len(p.IdEol) > 0 && !p.selectsIdEol(row) {
+					continue
+				}
+				log.Warn().Err(metadataErr).Msgf("Policy '%s' cannot be evaluated for %s", p.ID, row.Software)
+				violations = append(violations, stackViolation{
+					Kind: violationPolicy, Rule: p.ID, Item: row.Software, Warning: p.Severity == "warning",
+					Message: fmt.Sprintf("%s %s: policy '%s' could not be evaluated: %v", row.Software, row.Version, p.ID, metadataErr),
+				})
+				continue
+			}
+			if !p.selects(row, metadata) {
+				continue
+			}
+			var reasons []string
+			if p.MinDaysToEol != nil {
+				if days, err := strconv.Atoi(row.Days); err == nil && days < *p.MinDaysToEol {
+					reasons = append(reasons, fmt.Sprintf("%d days to EOL, at least %d required", days, *p.MinDaysToEol))
+				}
+			}
+			if p.MaxMajorsBehind != nil {
+				if behind, ok := majorsBehind(row.Version, metadata.Releases); ok && behind > *p.MaxMajorsBehind {
+					reasons = append(reasons, fmt.Sprintf("%d major versions behind (latest: %s), at most %d allowed", behind, row.LatestVersion, *p.MaxMajorsBehind))
+				}
+			}
+			if p.RequireLts && !row.IsLts {
+				reasons = append(reasons, "not an active LTS version")
+			}
+			if len(reasons) == 0 {
+				continue
+			}
+			violations = append(violations, stackViolation{
+				Kind: violationPolicy, Rule: p.ID, Item: row.Software, Warning: p.Severity == "warning",
+				Message: fmt.Sprintf("%s %s breaks policy '%s': %s", row.Software, row.Version, p.ID, strings.Join(reasons, ", ")),
+			})
+		}
+	}
+	return violations
+}
+
+// selects returns true when the policy applies to the row.
+func (p policyRule) selects(row stackTableRow, metadata productMetadata) bool {
+	if p.Category != "" && !strings.EqualFold(p.Category, metadata.Category) {
+		return false
+	}
+	if p.Tag != "" && !slices.ContainsFunc(metadata.Tags, func(tag string) bool { return strings.EqualFold(tag, p.Tag) }) {
+		return false
+	}
+	if len(p.IdEol) > 0 && !p.selectsIdEol(row) {
+		return false
+	}
+	return true
+}
+
+// selectsIdEol returns true when the id_eol selector of the policy lists the product of the row.
+func (p policyRule) selectsIdEol(row stackTableRow) bool {
+	return slices.Contains(p.IdEol, row.IdEol) || slices.Contains(p.IdEol, canonicalProductName(row.IdEol))
+}
+
+// needsMetadata returns true when the policy selects or checks on the product metadata.
+func (p policyRule) needsMetadata() bool {
+	return p.Category != "" || p.Tag != "" || p.MaxMajorsBehind != nil
+}
@@ -0,0 +1,240 @@
+package check
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/opt-nc/geol/v2/cmd/templates"
+	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	CheckCmd.AddCommand(ReportCmd)
+	ReportCmd.Flags().StringP("file", "f", ".geol.yaml", "Stack file to report on")
+	ReportCmd.Flags().String("html", "geol-report.html", "Path to the HTML report to write")
+	ReportCmd.Flags().StringP("env", "e", "", "Environment whose overlay is applied to the stack (see environments in the stack file)")
+	ReportCmd.Flags().StringP("date", "d", "", "Reference date for EOL calculations (format YYYY-MM-DD, default: today)")
+}
+
+// ReportCmd represents the check report command
+var ReportCmd = &cobra.Command{
+	Use:     "report",
+	Aliases: []string{"r"},
+	Short:   "Generate a self-contained HTML report of a stack.",
+	Long: `The 'report' command evaluates the stack file like 'geol check' and writes a single HTML file with the stack score gauge, the debt score of each component, an EOL timeline chart and the list of violations.
+The report embeds its styles and charts, so it can be opened offline and shared as is.`,
+	Example: `geol check report
+geol check report --file stack.yaml --html stack-report.html
+geol check report --date 2027-01-01`,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		htmlPath, _ := cmd.Flags().GetString("html")
+		env, _ := cmd.Flags().GetString("env")
+		input := readStackFile(file, env)
+
+		utilities.AnalyzeCacheProductsValidity(cmd)
+		today := referenceDate(cmd)
+		result, err := evaluateStack(input, today)
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+
+		f, err := os.Create(htmlPath)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("Error creating %s", htmlPath)
+		}
+		if err := writeHTMLReport(f, result, today); err != nil {
+			_ = f.Close()
+			log.Fatal().Err(err).Msg("Error generating the HTML report")
+		}
+		if err := f.Close(); err != nil {
+			log.Fatal().Err(err).Msgf("Error writing %s", htmlPath)
+		}
+		log.Info().Msgf("Report written to %s", htmlPath)
+	},
+}
+
+// reportColors are the colors of the report, the same as the geol-check-report.qmd notebook.
+var reportColors = map[string]string{
+	"green":  "#50fa7b",
+	"orange": "#ffb86c",
+	"red":    "#ff5555",
+}
+
+// scoreColor returns the report color of a 0-100 score, with the bands of computeStackScore.
+func scoreColor(value int, bands utilities.ScoringBands) string {
+	switch {
+	case value >= bands.Healthy:
+		return reportColors["green"]
+	case value >= bands.NeedsAttention:
+		return reportColors["orange"]
+	default:
+		return reportColors["red"]
+	}
+}
+
+type htmlComponent struct {
+	stackTableRow
+	Color string
+	Lts   string
+}
+
+type htmlTimelineBar struct {
+	Label, EolDate, Color string
+	Y, X, Width           float64
+}
+
+type htmlTimelineTick struct {
+	Label string
+	X     float64
+}
+
+// htmlTimeline is the EOL timeline chart, laid out in SVG user units.
+type htmlTimeline struct {
+	Width, Height, TodayX float64
+	Ticks                 []htmlTimelineTick
+	Bars                  []htmlTimelineBar
+}
+
+type htmlReport struct {
+	Title         string
+	ReferenceDate string
+	GeneratedAt   string
+	Version       string
+	Score         stackScore
+	ScoreColor    string
+	GaugePath     string
+	Eol, Warn, OK int
+	Skipped       []stackItem
+	Components    []htmlComponent
+	Timeline      htmlTimeline
+	Violations    []stackViolation
+}
+
+// gaugePath returns the SVG path of the arc of a 0-100 value on a half circle gauge of radius 90
+// centered at (110, 110).
+func gaugePath(value int) string {
+	angle := math.Pi * (1 - float64(min(max(value, 0), 100))/100)
+	return fmt.Sprintf("M 20 110 A 90 90 0 0 1 %.2f %.2f", 110+90*math.Cos(angle), 110-90*math.Sin(angle))
+}
+
+// Layout of the timeline chart: labels on the left, then one bar per component with an EOL date.
+const (
+	timelineLabelWidth = 180.0
+	timelineChartWidth = 700.0
+	timelineRowHeight  = 28.0
+	timelineAxisHeight = 30.0
+)
+
+// buildTimeline lays out a bar per component from the reference date to its EOL date, red when
+// past EOL, orange when nearing EOL (within riskThresholdDays) and green otherwise, with a tick per year.
+func buildTimeline(rows []stackTableRow, today time.Time, riskThresholdDays int) htmlTimeline {
+	type dated struct {
+		row stackTableRow
+		eol time.Time
+	}
+	var items []dated
+	from, to := today, today
+	for _, r := range rows {
+		eol, err := time.Parse("2006-01-02", r.EolDate)
+		if err != nil {
+			continue
+		}
+		items = append(items, dated{r, eol})
+		if eol.Before(from) {
+			from = eol
+		}
+		if eol.After(to) {
+			to = eol
+		}
+	}
+	// Pad the range so that bars do not touch the edges, and keep at least a year visible
+	from = from.AddDate(0, -3, 0)
+	to = to.AddDate(0, 3, 0)
+	if to.Sub(from) < 365*24*time.Hour {
+		to = from.AddDate(1, 0, 0)
+	}
+	span := to.Sub(from).Hours()
+	x := func(t time.Time) float64 {
+		return math.Round(10*(timelineLabelWidth+timelineChartWidth*t.Sub(from).Hours()/span)) / 10
+	}
+
+	timeline := htmlTimeline{
+		Width:  timelineLabelWidth + timelineChartWidth + 20,
+		Height: timelineAxisHeight + timelineRowHeight*float64(len(items)) + 10,
+		TodayX: x(today),
+	}
+	for year := from.Year() + 1; year <= to.Year(); year++ {
+		timeline.Ticks = append(timeline.Ticks, htmlTimelineTick{Label: strconv.Itoa(year), X: x(time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC))})
+	}
+	for i, item := range items {
+		start, end := today, item.eol
+		color := reportColors["green"]
+		switch {
+		case item.eol.Before(today):
+			start, end = item.eol, today
+			color = reportColors["red"]
+		case item.eol.Sub(today) < time.Duration(riskThresholdDays)*24*time.Hour:
+			color = reportColors["orange"]
+		}
+		timeline.Bars = append(timeline.Bars, htmlTimelineBar{
+			Label:   item.row.Software + " " + item.row.Version,
+			EolDate: item.row.EolDate,
+			Color:   color,
+			Y:       timelineAxisHeight + timelineRowHeight*float64(i),
+			X:       x(start),
+			Width:   max(math.Round(10*(x(end)-x(start)))/10, 2),
+		})
+	}
+	return timeline
+}
+
+// writeHTMLReport writes the stack report as a single HTML file, with no external resources.
+func writeHTMLReport(w io.Writer, result stackResult, today time.Time) error {
+	tmpl, err := template.New("report").Parse(templates.CheckReportTemplate)
+	if err != nil {
+		return err
+	}
+
+	report := htmlReport{
+		Title:         result.Title,
+		ReferenceDate: today.Format("2006-01-02"),
+		GeneratedAt:   time.Now().Format("2006-01-02 15:04"),
+		Version:       utilities.Version,
+		Score:         result.Score,
+		ScoreColor:    scoreColor(result.Score.Value, result.Scoring.Bands),
+		GaugePath:     gaugePath(result.Score.Value),
+		Timeline:      buildTimeline(result.Rows, today, result.Scoring.RiskThresholdDays),
+		Violations:    result.Violations,
+	}
+	for _, item := range result.Stack {
+		if item.Skip {
+			report.Skipped = append(report.Skipped, item)
+		}
+	}
+	for _, r := range result.Rows {
+		switch r.Status {
+		case "EOL":
+			report.Eol++
+		case "WARN":
+			report.Warn++
+		default:
+			report.OK++
+		}
+		lts := "—"
+		if r.IsLatestLts {
+			lts = "Latest"
+		} else if r.IsLts {
+			lts = "Older"
+		}
+		report.Components = append(report.Components, htmlComponent{stackTableRow: r, Color: scoreColor(r.DebtScore, result.Scoring.Bands), Lts: lts})
+	}
+	return tmpl.Execute(w, report)
+}
@@ -0,0 +1,186 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/opt-nc/geol/v2/utilities"
+)
+
+// sarifVersion is the SARIF specification version of the logs geol writes.
+const sarifVersion = "2.1.0"
+
+// sarifRule describes a kind of stack violation. Its id is the violation kind, which must stay
+// stable across releases since code scanning tools track results by rule id.
+type sarifRule struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	ShortDescription struct {
+		Text string `json:"text"`
+	} `json:"shortDescription"`
+	HelpURI              string `json:"helpUri"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region *sarifRegion `json:"region,omitempty"`
+	} `json:"physicalLocation"`
+}
+
+type sarifResult struct {
+	RuleID    string `json:"ruleId"`
+	RuleIndex int    `json:"ruleIndex"`
+	Level     string `json:"level"`
+	Message   struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	Locations    []sarifLocation    `json:"locations,omitempty"`
+	Suppressions []sarifSuppression `json:"suppressions,omitempty"`
+}
+
+// sarifSuppression records the waiver of a result, so that code scanning tools dismiss it.
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Status        string `json:"status"`
+	Justification string `json:"justification"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name           string      `json:"name"`
+			Version        string      `json:"version"`
+			InformationURI string      `json:"informationUri"`
+			Rules          []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// sarifRules are the rules of every violation kind, in a fixed order so that rule indexes are stable.
+var sarifRules = []struct {
+	kind, name, description, level string
+}{
+	{violationPastEol, "PastEol", "The component is past its end-of-life date", "error"},
+	{violationNearingEol, "NearingEol", "The component reaches its end-of-life date in less than 30 days", "warning"},
+	{violationNotLatest, "NotLatest", "The component must always be in its latest version (always-latest)", "warning"},
+	{violationLtsPolicy, "LtsPolicy", "The component does not follow its LTS policy (lts_strategy)", "error"},
+	{violationInvalidManualEol, "InvalidManualEol", "The manual_eol date of the component is not a valid YYYY-MM-DD date", "error"},
+	{violationExpiredWaiver, "ExpiredWaiver", "The waiver of the component has expired", "error"},
+}
+
+// sarifArtifactURI returns the URI of file for a SARIF artifact location: a relative path when
+// the file is in the working directory, so that code scanning tools can match it to the
+// repository, or a file URI otherwise.
+func sarifArtifactURI(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, abs); err == nil && filepath.IsLocal(rel) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
+
+// writeSarif writes the violations of the stacks as a SARIF log, one result per violation located
+// at the line where its stack item is defined, or overridden by an environment.
+func writeSarif(w io.Writer, results []stackResult) error {
+	var run sarifRun
+	run.Tool.Driver.Name = "geol"
+	run.Tool.Driver.Version = utilities.Version
+	run.Tool.Driver.InformationURI = "https://github.com/opt-nc/geol"
+
+	ruleIndexes := map[string]int{}
+	for i, r := range sarifRules {
+		var rule sarifRule
+		rule.ID = r.kind
+		rule.Name = r.name
+		rule.ShortDescription.Text = r.description
+		rule.HelpURI = "https://opt-nc.github.io/geol/docs/command-reference/check"
+		rule.DefaultConfiguration.Level = r.level
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		ruleIndexes[r.kind] = i
+	}
+	// Policies come after the built-in rules, so that the indexes of the built-in rules never change
+	for _, stack := range results {
+		for _, p := range stack.Policies {
+			id := stackViolation{Kind: violationPolicy, Rule: p.ID}.RuleID()
+			if _, ok := ruleIndexes[id]; ok {
+				continue
+			}
+			var rule sarifRule
+			rule.ID = id
+			rule.Name = p.ID
+			rule.ShortDescription.Text = p.Description
+			if rule.ShortDescription.Text == "" {
+				rule.ShortDescription.Text = "Stack policy " + p.ID
+			}
+			rule.HelpURI = "https://opt-nc.github.io/geol/docs/command-reference/check"
+			rule.DefaultConfiguration.Level = p.Severity
+			ruleIndexes[id] = len(run.Tool.Driver.Rules)
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		}
+	}
+
+	run.Results = []sarifResult{}
+	for _, stack := range results {
+		for _, v := range stack.Violations {
+			result := sarifResult{RuleID: v.RuleID(), RuleIndex: ruleIndexes[v.RuleID()], Level: "error"}
+			if !v.failsStrict() {
+				result.Level = "warning"
+			}
+			result.Message.Text = v.Message
+			if i := slices.IndexFunc(stack.Stack, func(item stackItem) bool { return item.Name == v.Item }); v.Waived && i >= 0 {
+				waiver := stack.Stack[i].Waiver
+				result.Suppressions = []sarifSuppression{{
+					Kind: "external", Status: "accepted",
+					Justification: fmt.Sprintf("%s (approved by %s, expires %s)", waiver.Reason, waiver.ApprovedBy, waiver.Expires),
+				}}
+			}
+			if stack.File != "" {
+				var location sarifLocation
+				location.PhysicalLocation.ArtifactLocation.URI = sarifArtifactURI(stack.File)
+				if defined, ok := stack.Locations[v.Item]; ok {
+					location.PhysicalLocation.ArtifactLocation.URI = sarifArtifactURI(defined.File)
+					if defined.Line > 0 {
+						location.PhysicalLocation.Region = &sarifRegion{StartLine: defined.Line}
+					}
+				}
+				result.Locations = []sarifLocation{location}
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	})
+}
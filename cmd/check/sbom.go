@@ -0,0 +1,222 @@
+package check
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/opt-nc/geol/v2/utilities"
+)
+
+// sbomComponent is a component (CycloneDX) or package (SPDX) of an SBOM.
+type sbomComponent struct {
+	Name    string
+	Version string
+	Purl    string
+	Cpe     string
+}
+
+// unmappedComponent is an SBOM component that could not be mapped to an endoflife.date product.
+type unmappedComponent struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Purl    string `json:"purl,omitempty"`
+	Cpe     string `json:"cpe,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// readSbom reads a CycloneDX or SPDX JSON document and returns its title and components.
+func readSbom(path string) (string, []sbomComponent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	var probe struct {
+		BomFormat   string `json:"bomFormat"`
+		SpdxVersion string `json:"spdxVersion"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return "", nil, fmt.Errorf("only JSON SBOMs are supported: %w", err)
+	}
+	switch {
+	case probe.BomFormat == "CycloneDX":
+		return readCycloneDX(data)
+	case probe.SpdxVersion != "":
+		return readSPDX(data)
+	default:
+		return "", nil, errors.New("not a CycloneDX (bomFormat) or SPDX (spdxVersion) JSON document")
+	}
+}
+
+type cycloneDXComponent struct {
+	Name       string               `json:"name"`
+	Version    string               `json:"version"`
+	Purl       string               `json:"purl"`
+	Cpe        string               `json:"cpe"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+func readCycloneDX(data []byte) (string, []sbomComponent, error) {
+	var bom struct {
+		Metadata struct {
+			Component cycloneDXComponent `json:"component"`
+		} `json:"metadata"`
+		Components []cycloneDXComponent `json:"components"`
+	}
+	if err := json.Unmarshal(data, &bom); err != nil {
+		return "", nil, err
+	}
+	var components []sbomComponent
+	var walk func([]cycloneDXComponent)
+	walk = func(list []cycloneDXComponent) {
+		for _, c := range list {
+			components = append(components, sbomComponent{Name: c.Name, Version: c.Version, Purl: c.Purl, Cpe: c.Cpe})
+			walk(c.Components)
+		}
+	}
+	walk(bom.Components)
+	return bom.Metadata.Component.Name, components, nil
+}
+
+func readSPDX(data []byte) (string, []sbomComponent, error) {
+	var doc struct {
+		Name     string `json:"name"`
+		Packages []struct {
+			Name         string `json:"name"`
+			VersionInfo  string `json:"versionInfo"`
+			ExternalRefs []struct {
+				ReferenceType    string `json:"referenceType"`
+				ReferenceLocator string `json:"referenceLocator"`
+			} `json:"externalRefs"`
+		} `json:"packages"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return "", nil, err
+	}
+	components := make([]sbomComponent, 0, len(doc.Packages))
+	for _, p := range doc.Packages {
+		c := sbomComponent{Name: p.Name, Version: p.VersionInfo}
+		for _, ref := range p.ExternalRefs {
+			switch ref.ReferenceType {
+			case "purl":
+				c.Purl = ref.ReferenceLocator
+			case "cpe23Type", "cpe22Type":
+				if c.Cpe == "" {
+					c.Cpe = ref.ReferenceLocator
+				}
+			}
+		}
+		components = append(components, c)
+	}
+	return doc.Name, components, nil
+}
+
+// purlKey returns a purl without its version, qualifiers and subpath, lowercased, so that a
+// component purl matches the versionless purls of endoflife.date. Docker official images are
+// matched with or without the library namespace.
+func purlKey(purl string) (key, version string) {
+	purl = strings.ToLower(purl)
+	purl, _, _ = strings.Cut(purl, "#")
+	purl, _, _ = strings.Cut(purl, "?")
+	if at := strings.LastIndex(purl, "@"); at > strings.LastIndex(purl, "/") {
+		purl, version = purl[:at], purl[at+1:]
+	}
+	return strings.Replace(purl, "pkg:docker/library/", "pkg:docker/", 1), version
+}
+
+// cpeKey returns the part:vendor:product of a CPE 2.2 (cpe:/a:vendor:product:version) or 2.3
+// (cpe:2.3:a:vendor:product:version:...) name, lowercased, along with its version.
+func cpeKey(cpe string) (key, version string) {
+	cpe = strings.ToLower(cpe)
+	var fields []string
+	switch {
+	case strings.HasPrefix(cpe, "cpe:2.3:"):
+		fields = strings.Split(strings.TrimPrefix(cpe, "cpe:2.3:"), ":")
+	case strings.HasPrefix(cpe, "cpe:/"):
+		fields = strings.Split(strings.TrimPrefix(cpe, "cpe:/"), ":")
+	default:
+		return "", ""
+	}
+	if len(fields) < 3 {
+		return "", ""
+	}
+	if len(fields) > 3 && fields[3] != "*" && fields[3] != "-" {
+		version = fields[3]
+	}
+	return strings.Join(fields[:3], ":"), version
+}
+
+// mapSbomComponents maps the components to endoflife.date products through the purl and CPE
+// identifiers of the products, and returns the discoveries along with the unmapped components.
+func mapSbomComponents(components []sbomComponent, identifiers utilities.IdentifiersFile) ([]discovery, []unmappedComponent) {
+	byPurl := map[string]string{}
+	byCpe := map[string]string{}
+	for prod, ids := range identifiers {
+		for _, id := range ids {
+			switch id.Type {
+			case "purl":
+				key, _ := purlKey(id.ID)
+				byPurl[key] = prod
+			case "cpe":
+				if key, _ := cpeKey(id.ID); key != "" {
+					byCpe[key] = prod
+				}
+			}
+		}
+	}
+
+	var found []discovery
+	var unmapped []unmappedComponent
+	for _, c := range components {
+		prod, version := "", c.Version
+		if c.Purl != "" {
+			key, purlVersion := purlKey(c.Purl)
+			prod = byPurl[key]
+			if version == "" {
+				version = purlVersion
+			}
+		}
+		if prod == "" && c.Cpe != "" {
+			key, cpeVersion := cpeKey(c.Cpe)
+			prod = byCpe[key]
+			if version == "" {
+				version = cpeVersion
+			}
+		}
+
+		version = strings.TrimPrefix(version, "v")
+		missing := unmappedComponent{Name: c.Name, Version: c.Version, Purl: c.Purl, Cpe: c.Cpe}
+		switch {
+		case c.Purl == "" && c.Cpe == "":
+			missing.Reason = "no purl or CPE"
+		case prod == "":
+			missing.Reason = "no matching endoflife.date identifier"
+		case version == "":
+			missing.Reason = "no version"
+		case !hasReleaseCycle(prod, version) && !hasReleaseCycle(prod, findVersionSuggestion(prod, version)):
+			missing.Reason = "no matching release cycle"
+		default:
+			found = append(found, discovery{candidates: []string{prod}, version: version, source: "SBOM component " + c.Name})
+			continue
+		}
+		unmapped = append(unmapped, missing)
+	}
+	sort.SliceStable(unmapped, func(i, j int) bool { return unmapped[i].Name < unmapped[j].Name })
+	return found, unmapped
+}
+
+// renderUnmappedTable renders the SBOM components that could not be mapped.
+func renderUnmappedTable(unmapped []unmappedComponent) string {
+	t := newStackTable("Unmapped Component", "Version", "Identifier", "Reason")
+	for _, c := range unmapped {
+		identifier := c.Purl
+		if identifier == "" {
+			identifier = c.Cpe
+		}
+		t.Row(c.Name, c.Version, identifier, c.Reason)
+	}
+	return t.Render()
+}
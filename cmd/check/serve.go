@@ -0,0 +1,169 @@
+package check
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	ServeCmd.Flags().String("addr", ":8080", "Address to serve the REST API on")
+	ServeCmd.Flags().String("metrics", "", "Address to serve the Prometheus metrics of the stacks on, e.g. :9099, or the --addr address to serve them along with the API")
+	ServeCmd.Flags().StringSliceP("file", "f", []string{".geol.yaml"}, "Stack file to evaluate for the metrics, can be repeated")
+	ServeCmd.Flags().StringP("recursive", "r", "", "Evaluate every .geol.yaml file found under a directory for the metrics")
+	ServeCmd.Flags().StringP("env", "e", "", "Environment whose overlay is applied to the stack files (see environments in the stack file)")
+	ServeCmd.Flags().Duration("refresh", time.Hour, "Time between two evaluations of the stacks of the metrics")
+}
+
+// ServeCmd represents the serve command
+var ServeCmd = &cobra.Command{
+	Use:     "serve",
+	Aliases: []string{"srv"},
+	Short:   "Serve geol's answers over HTTP.",
+	Long: `The 'serve' command serves a REST API over HTTP, so that other tools get geol's answers without running the CLI. It reads the endoflife.date data through the local cache, like the other commands:
+- GET /health: the status of the server and of its cache
+- GET /openapi.json: the OpenAPI description of the API
+- GET /v1/products/{product}: the product name and aliases of a product or alias
+- GET /v1/products/{product}/cycles: the release cycles of a product, like 'geol product extended --json'
+- POST /v1/check: the 'geol check --json' output of the stack document of the body (same schema as .geol.yaml), with the env and date query parameters
+
+With --metrics, the stack files are also evaluated like 'geol check', again every --refresh interval, and exposed as Prometheus gauges on /metrics: the days until EOL, debt score, latest and LTS compliance of every component, labelled by app_id, component, id_eol and version, and the score of every stack. Alert on EOL from Prometheus and Grafana instead of parsing the output of 'geol check'.`,
+	Example: `geol serve
+curl -X POST --data-binary @.geol.yaml http://localhost:8080/v1/check?env=prod
+geol serve --metrics :9099
+geol serve --addr :8080 --metrics :8080 --recursive . --refresh 6h
+geol serve --metrics :9099 --file api/.geol.yaml --file web/.geol.yaml --env prod`,
+	Run: func(cmd *cobra.Command, args []string) {
+		addr, _ := cmd.Flags().GetString("addr")
+		metricsAddr, _ := cmd.Flags().GetString("metrics")
+		files, _ := cmd.Flags().GetStringSlice("file")
+		env, _ := cmd.Flags().GetString("env")
+		refresh, _ := cmd.Flags().GetDuration("refresh")
+		if refresh <= 0 {
+			log.Fatal().Msg("--refresh must be a positive duration")
+		}
+		utilities.AnalyzeCacheProductsValidity(cmd)
+
+		mux := http.NewServeMux()
+		api := apiServer{cmd: cmd, cache: &cacheHealth{}}
+		go func() {
+			for range time.Tick(cacheCheckInterval) {
+				api.refreshCache()
+			}
+		}()
+		mux.HandleFunc("GET /health", api.handleHealth)
+		mux.HandleFunc("GET /openapi.json", api.handleOpenAPI)
+		mux.HandleFunc("GET /v1/products/{product}", api.handleProduct)
+		mux.HandleFunc("GET /v1/products/{product}/cycles", api.handleCycles)
+		mux.HandleFunc("POST /v1/check", api.handleCheck)
+
+		if metricsAddr != "" {
+			if dir, _ := cmd.Flags().GetString("recursive"); dir != "" {
+				found, err := findStackFiles(dir)
+				if err != nil {
+					log.Fatal().Err(err).Msgf("Error searching stack files in %s", dir)
+				}
+				if len(found) == 0 {
+					log.Fatal().Msgf("No .geol.yaml file found in %s", dir)
+				}
+				files = found
+			}
+			stacks := &servedStacks{stackFiles: readStackFiles(files, env)}
+			stacks.evaluate(cmd)
+			go func() {
+				for range time.Tick(refresh) {
+					stacks.reload()
+					stacks.evaluate(cmd)
+				}
+			}()
+
+			if metricsAddr == addr {
+				mux.HandleFunc("GET /metrics", stacks.handleMetrics)
+			} else {
+				metricsMux := http.NewServeMux()
+				metricsMux.HandleFunc("GET /metrics", stacks.handleMetrics)
+				go func() {
+					if err := newHTTPServer(metricsAddr, metricsMux).ListenAndServe(); err != nil {
+						log.Fatal().Err(err).Msg("Error running the metrics server")
+					}
+				}()
+			}
+			log.Info().Msgf("Serving the metrics of %d stack(s) on %s/metrics, refreshed every %s", len(files), metricsAddr, refresh)
+		}
+
+		log.Info().Msgf("Serving the API on %s (see /openapi.json)", addr)
+		if err := newHTTPServer(addr, mux).ListenAndServe(); err != nil {
+			log.Fatal().Err(err).Msg("Error running the API server")
+		}
+	},
+}
+
+// newHTTPServer returns a server of handler on addr.
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// evaluationLock serializes the evaluations of the server, which share the memoized product
+// payloads (see prefetchProductBodies).
+var evaluationLock sync.Mutex
+
+// servedStacks are the stacks evaluated by the server, and their last results.
+type servedStacks struct {
+	stackFiles
+
+	mu        sync.RWMutex
+	results   []stackResult
+	evaluated time.Time
+}
+
+// evaluate evaluates the stacks as of now and replaces the served results. A stack that cannot
+// be evaluated keeps its previous results, and a failed refresh of the cache is logged, the stacks
+// being evaluated with the cached data.
+func (s *servedStacks) evaluate(cmd *cobra.Command) {
+	evaluationLock.Lock()
+	if err := utilities.RefreshStaleCache(cmd); err != nil {
+		log.Warn().Err(err).Msg("Error refreshing the cache, evaluating with the cached data")
+	}
+	now := time.Now()
+	var results []stackResult
+	for _, input := range s.inputs {
+		result, err := evaluateStack(input, now)
+		if err != nil {
+			log.Error().Msgf("%s: %v, keeping its previous evaluation", input.appKey(), err)
+			s.mu.RLock()
+			i := slices.IndexFunc(s.results, func(r stackResult) bool { return r.appKey() == input.appKey() })
+			if i >= 0 {
+				results = append(results, s.results[i])
+			}
+			s.mu.RUnlock()
+			continue
+		}
+		results = append(results, result)
+	}
+	evaluationLock.Unlock()
+
+	s.mu.Lock()
+	s.results, s.evaluated = results, now
+	s.mu.Unlock()
+	log.Info().Msgf("Evaluated %d stack(s)", len(results))
+}
+
+// handleMetrics writes the last results in the Prometheus text format.
+func (s *servedStacks) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	results, evaluated := s.results, s.evaluated
+	s.mu.RUnlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := writeMetrics(w, results, evaluated); err != nil {
+		log.Error().Err(err).Msg("Error writing the metrics")
+	}
+}
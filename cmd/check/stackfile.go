@@ -0,0 +1,276 @@
+package check
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/opt-nc/geol/v2/utilities"
+	"gopkg.in/yaml.v3"
+)
+
+// stackFile is a stack file as written, before its base file and environment overlays are resolved.
+type stackFile struct {
+	GeolVersion  string                   `yaml:"geolVersion"`
+	Extends      string                   `yaml:"extends,omitempty"`
+	AppName      string                   `yaml:"app_name"`
+	AppID        string                   `yaml:"app_id"`
+	Stack        []stackItem              `yaml:"stack"`
+	Environments map[string]stackOverlay  `yaml:"environments,omitempty"`
+	Policies     []policyRule             `yaml:"policies,omitempty"`
+	Notify       []utilities.NotifyTarget `yaml:"notify,omitempty"`
+	// Scoring is decoded over the scoring model of the base file, so that unset fields are inherited
+	Scoring yaml.Node `yaml:"scoring,omitempty"`
+}
+
+// stackOverlay overrides the version and policy fields of stack items for an environment.
+type stackOverlay struct {
+	Stack []stackItemOverride `yaml:"stack"`
+}
+
+// stackItemOverride is a stack item of an overlay: the fields left unset keep their value.
+type stackItemOverride struct {
+	Name                 string       `yaml:"name"`
+	Version              *string      `yaml:"version"`
+	IdEol                *string      `yaml:"id_eol"`
+	Skip                 *bool        `yaml:"skip"`
+	ShouldAlwaysBeLatest *bool        `yaml:"always-latest"`
+	ManualEol            *string      `yaml:"manual_eol"`
+	LtsStrategy          *string      `yaml:"lts_strategy"`
+	LtsGraceDays         *int         `yaml:"lts_grace_days"`
+	Waiver               *stackWaiver `yaml:"waiver"`
+}
+
+// stackLocation is the file and line where a stack item is defined, or last overridden.
+type stackLocation struct {
+	File string
+	Line int
+}
+
+// resolvedStack is a stack file with its base files and the overlays of an environment applied.
+type resolvedStack struct {
+	geolConfig
+	GeolVersion string
+	// Locations maps the stack item names to where they are defined
+	Locations map[string]stackLocation
+	// Layered is true when the stack comes from several files or from an environment overlay
+	Layered bool
+	// Environments are the names of the environments defined by the file and its base files
+	Environments []string
+	// Scoring is the scoring model of the configuration file, overridden by the scoring: blocks
+	Scoring utilities.ScoringModel
+}
+
+// overlayLayer is the overlay of an environment in one of the files of an extends chain.
+type overlayLayer struct {
+	file    string
+	overlay stackOverlay
+	lines   map[string]int
+}
+
+// resolveStackFile reads a stack file, merges it over its extends chain, then applies the
+// overlays of env when env is not empty.
+func resolveStackFile(file, env string) (resolvedStack, error) {
+	return resolveStack(file, nil, env)
+}
+
+// resolveStackDocument resolves a stack document that is not read from a file, such as a stack
+// posted to 'geol serve', named name in the errors. It cannot extend a base file.
+func resolveStackDocument(name string, data []byte, env string) (resolvedStack, error) {
+	return resolveStack(name, data, env)
+}
+
+// resolveStack resolves the stack file, or the data document named file when data is not nil.
+func resolveStack(file string, data []byte, env string) (resolvedStack, error) {
+	resolved := resolvedStack{Locations: map[string]stackLocation{}, Scoring: utilities.Scoring}
+	var layers []overlayLayer
+	if err := resolveExtends(file, data, env, &resolved, &layers, nil); err != nil {
+		return resolved, err
+	}
+	if err := resolved.Scoring.Validate(); err != nil {
+		return resolved, fmt.Errorf("%s: %w", file, err)
+	}
+	if env == "" {
+		return resolved, nil
+	}
+	if !slices.Contains(resolved.Environments, env) {
+		if len(resolved.Environments) == 0 {
+			return resolved, fmt.Errorf("%s defines no environments, cannot select environment %q", file, env)
+		}
+		return resolved, fmt.Errorf("environment %q is not defined in %s (available: %v)", env, file, resolved.Environments)
+	}
+	resolved.Layered = true
+	for _, layer := range layers {
+		for _, override := range layer.overlay.Stack {
+			i := slices.IndexFunc(resolved.Stack, func(item stackItem) bool { return item.Name == override.Name })
+			if i < 0 {
+				return resolved, fmt.Errorf("%s: environment %q overrides %q, which is not a stack item", layer.file, env, override.Name)
+			}
+			if override.IdEol != nil {
+				return resolved, fmt.Errorf("%s: environment %q cannot override the id_eol of %q, only its version and policy fields", layer.file, env, override.Name)
+			}
+			override.apply(&resolved.Stack[i])
+			resolved.Locations[override.Name] = stackLocation{File: layer.file, Line: layer.lines[override.Name]}
+		}
+	}
+	return resolved, nil
+}
+
+// resolveExtends merges file over its base files into resolved, and collects the overlays of env
+// from the base file to the extending one. chain holds the files being resolved, to detect cycles.
+// When data is not nil, it is the document of file, which is not read and cannot extend a base file.
+func resolveExtends(file string, data []byte, env string, resolved *resolvedStack, layers *[]overlayLayer, chain []string) error {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(chain, abs) {
+		return fmt.Errorf("extends cycle: %s extends itself through %v", file, chain)
+	}
+
+	inMemory := data != nil
+	if !inMemory {
+		if data, err = os.ReadFile(file); err != nil {
+			return err
+		}
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("YAML format error in %s: %w", file, err)
+	}
+	if len(doc.Content) == 0 {
+		return errors.New("empty stack file: " + file)
+	}
+	var sf stackFile
+	if err := doc.Decode(&sf); err != nil {
+		return fmt.Errorf("YAML format error in %s: %w", file, err)
+	}
+	root := doc.Content[0]
+
+	if sf.Extends != "" {
+		if inMemory {
+			return fmt.Errorf("%s: extends is only supported in stack files", file)
+		}
+		base := sf.Extends
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(filepath.Dir(file), base)
+		}
+		if err := resolveExtends(base, nil, env, resolved, layers, append(chain, abs)); err != nil {
+			return err
+		}
+		resolved.Layered = true
+	}
+
+	if sf.GeolVersion != "" {
+		resolved.GeolVersion = sf.GeolVersion
+	}
+	if sf.AppName != "" {
+		resolved.AppName = sf.AppName
+	}
+	if sf.AppID != "" {
+		resolved.AppID = sf.AppID
+	}
+	lines := sequenceItemLines(mappingValue(root, "stack"))
+	for _, item := range sf.Stack {
+		if i := slices.IndexFunc(resolved.Stack, func(inherited stackItem) bool { return inherited.Name == item.Name }); i >= 0 {
+			resolved.Stack[i] = item
+		} else {
+			resolved.Stack = append(resolved.Stack, item)
+		}
+		resolved.Locations[item.Name] = stackLocation{File: file, Line: lines[item.Name]}
+	}
+
+	for _, policy := range sf.Policies {
+		if i := slices.IndexFunc(resolved.Policies, func(inherited policyRule) bool { return inherited.ID == policy.ID }); i >= 0 {
+			resolved.Policies[i] = policy
+		} else {
+			resolved.Policies = append(resolved.Policies, policy)
+		}
+	}
+
+	if len(sf.Notify) > 0 {
+		resolved.Notify = sf.Notify
+	}
+
+	if sf.Scoring.Kind != 0 {
+		if err := sf.Scoring.Decode(&resolved.Scoring); err != nil {
+			return fmt.Errorf("invalid scoring in %s: %w", file, err)
+		}
+	}
+
+	for name := range sf.Environments {
+		if !slices.Contains(resolved.Environments, name) {
+			resolved.Environments = append(resolved.Environments, name)
+		}
+	}
+	sort.Strings(resolved.Environments)
+	if overlay, ok := sf.Environments[env]; env != "" && ok {
+		var overlayLines map[string]int
+		if envs := mappingValue(root, "environments"); envs != nil {
+			if node := mappingValue(envs, env); node != nil {
+				overlayLines = sequenceItemLines(mappingValue(node, "stack"))
+			}
+		}
+		*layers = append(*layers, overlayLayer{file: file, overlay: overlay, lines: overlayLines})
+	}
+	return nil
+}
+
+// apply sets the fields of the override on item.
+func (override stackItemOverride) apply(item *stackItem) {
+	if override.Version != nil {
+		item.Version = *override.Version
+	}
+	if override.Skip != nil {
+		item.Skip = *override.Skip
+	}
+	if override.ShouldAlwaysBeLatest != nil {
+		item.ShouldAlwaysBeLatest = *override.ShouldAlwaysBeLatest
+	}
+	if override.ManualEol != nil {
+		item.ManualEol = *override.ManualEol
+	}
+	if override.LtsStrategy != nil {
+		item.LtsStrategy = *override.LtsStrategy
+	}
+	if override.LtsGraceDays != nil {
+		item.LtsGraceDays = *override.LtsGraceDays
+	}
+	if override.Waiver != nil {
+		item.Waiver = override.Waiver
+	}
+}
+
+// validateResolvedStack validates the merged stack against the geol_stack.cue schema.
+func validateResolvedStack(file string, resolved resolvedStack) error {
+	data, err := yaml.Marshal(struct {
+		GeolVersion string                   `yaml:"geolVersion,omitempty"`
+		AppName     string                   `yaml:"app_name,omitempty"`
+		AppID       string                   `yaml:"app_id,omitempty"`
+		Stack       []stackItem              `yaml:"stack"`
+		Policies    []policyRule             `yaml:"policies,omitempty"`
+		Scoring     utilities.ScoringModel   `yaml:"scoring"`
+		Notify      []utilities.NotifyTarget `yaml:"notify,omitempty"`
+	}{resolved.GeolVersion, resolved.AppName, resolved.AppID, resolved.Stack, resolved.Policies, resolved.Scoring, resolved.Notify})
+	if err != nil {
+		return err
+	}
+	return utilities.ValidateStack(file, data)
+}
+
+// sequenceItemLines returns the line of every item of a sequence of mappings, keyed by item name.
+func sequenceItemLines(seq *yaml.Node) map[string]int {
+	lines := map[string]int{}
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return lines
+	}
+	for _, item := range seq.Content {
+		if name := mappingValue(item, "name"); name != nil {
+			lines[name.Value] = item.Line
+		}
+	}
+	return lines
+}
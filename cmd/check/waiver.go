@@ -0,0 +1,80 @@
+package check
+
+import (
+	"fmt"
+	"time"
+)
+
+// violationExpiredWaiver is the kind of the violations raised by waivers past their expiry date.
+const violationExpiredWaiver = "expired-waiver"
+
+// stackWaiver is the waiver: block of a stack item. Until it expires, the violations of the item
+// are reported as warnings and do not fail the check in strict mode.
+type stackWaiver struct {
+	Reason     string `yaml:"reason" json:"reason"`
+	ApprovedBy string `yaml:"approved_by" json:"approved_by"`
+	// Expires is the last day of the waiver (YYYY-MM-DD)
+	Expires string `yaml:"expires" json:"expires"`
+}
+
+// checkWaiver returns the errors of the waiver of the stack item at index i.
+func checkWaiver(i int, waiver *stackWaiver) []string {
+	if waiver == nil {
+		return nil
+	}
+	var errs []string
+	if waiver.Reason == "" {
+		errs = append(errs, fmt.Sprintf("stack[%d].waiver.reason is required", i))
+	}
+	if waiver.ApprovedBy == "" {
+		errs = append(errs, fmt.Sprintf("stack[%d].waiver.approved_by is required", i))
+	}
+	if _, err := time.Parse("2006-01-02", waiver.Expires); err != nil {
+		errs = append(errs, fmt.Sprintf("stack[%d].waiver.expires must be a YYYY-MM-DD date, got '%s'", i, waiver.Expires))
+	}
+	return errs
+}
+
+// activeOn returns true when the waiver has not expired on the today reference date.
+func (waiver stackWaiver) activeOn(today time.Time) bool {
+	expires, err := time.Parse("2006-01-02", waiver.Expires)
+	return err == nil && today.Before(expires.AddDate(0, 0, 1))
+}
+
+// applyWaivers marks the rows of the waived stack items, turns their violations into warnings,
+// and adds a violation for every expired waiver.
+func applyWaivers(stack []stackItem, rows []stackTableRow, violations []stackViolation, today time.Time) []stackViolation {
+	active := map[string]stackWaiver{}
+	for _, item := range stack {
+		if item.Waiver == nil || item.Skip {
+			continue
+		}
+		if item.Waiver.activeOn(today) {
+			active[item.Name] = *item.Waiver
+			continue
+		}
+		violations = append(violations, stackViolation{
+			Kind: violationExpiredWaiver, Item: item.Name,
+			Message: fmt.Sprintf("%s %s: waiver approved by %s expired on %s (%s)", item.Name, item.Version, item.Waiver.ApprovedBy, item.Waiver.Expires, item.Waiver.Reason),
+		})
+	}
+	for i := range rows {
+		for _, item := range stack {
+			if item.Name == rows[i].Software && item.Waiver != nil {
+				waiver := *item.Waiver
+				rows[i].Waiver = &waiver
+				_, rows[i].Waived = active[item.Name]
+			}
+		}
+	}
+	for i, v := range violations {
+		waiver, ok := active[v.Item]
+		if !ok || v.Warning {
+			continue
+		}
+		violations[i].Warning = true
+		violations[i].Waived = true
+		violations[i].Message = fmt.Sprintf("%s [waived until %s by %s: %s]", v.Message, waiver.Expires, waiver.ApprovedBy, waiver.Reason)
+	}
+	return violations
+}
@@ -0,0 +1,357 @@
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"charm.land/lipgloss/v2"
+	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	WatchCmd.Flags().StringSliceP("file", "f", []string{".geol.yaml"}, "Stack file to watch, can be repeated")
+	WatchCmd.Flags().StringP("env", "e", "", "Environment whose overlay is applied to the stack files (see environments in the stack file)")
+	WatchCmd.Flags().Duration("interval", 6*time.Hour, "Time between two checks of the stacks")
+	WatchCmd.Flags().Duration("poll", 2*time.Second, "Time between two looks at the stack files for changes")
+	WatchCmd.Flags().Bool("once", false, "Check the stacks once, report the changes since the previous run and exit")
+	WatchCmd.Flags().Bool("json", false, "Output the changes as JSON lines")
+}
+
+// WatchCmd represents the watch command
+var WatchCmd = &cobra.Command{
+	Use:     "watch",
+	Aliases: []string{"w"},
+	Short:   "Monitor stacks and report their lifecycle changes.",
+	Long: `The 'watch' command checks stack files periodically (--interval), and whenever one of them, or one of the files it extends, changes on disk. It only reports what changed since the previous check: a component was added, removed or changed version, entered the risk window of the scoring model (180 days before EOL by default), became EOL, a new latest version was released, the latest LTS cycle of a component with lts_strategy: latest moved, or the stack score changed.
+The last evaluation of each app is kept in watch-state.json in the geol config directory, so that a restarted watch, or a scheduled 'geol watch --once', reports the changes since the previous run. The first check of an app only records its baseline.`,
+	Example: `geol watch
+geol watch --file api/.geol.yaml --file web/.geol.yaml --interval 1h
+geol watch --env prod --json >> geol-events.jsonl
+geol watch --once`,
+	Run: func(cmd *cobra.Command, args []string) {
+		files, _ := cmd.Flags().GetStringSlice("file")
+		env, _ := cmd.Flags().GetString("env")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		poll, _ := cmd.Flags().GetDuration("poll")
+		once, _ := cmd.Flags().GetBool("once")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		if interval <= 0 || poll <= 0 {
+			log.Fatal().Msg("--interval and --poll must be positive durations")
+		}
+
+		statePath, err := watchStatePath()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error retrieving the watch state path")
+		}
+		w := &stackWatcher{stackFiles: readStackFiles(files, env), jsonOutput: jsonOutput, statePath: statePath, state: watchState{}}
+		if data, err := os.ReadFile(statePath); err == nil {
+			if err := json.Unmarshal(data, &w.state); err != nil {
+				log.Warn().Err(err).Msgf("Ignoring the invalid watch state %s", statePath)
+			}
+		}
+		utilities.AnalyzeCacheProductsValidity(cmd)
+		w.check(cmd)
+		if once {
+			return
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		modTimes := w.modTimes()
+		checkTicker := time.NewTicker(interval)
+		defer checkTicker.Stop()
+		pollTicker := time.NewTicker(poll)
+		defer pollTicker.Stop()
+		log.Info().Msgf("Watching %d stack file(s), checking every %s", len(files), interval)
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info().Msg("Stopped watching")
+				return
+			case <-checkTicker.C:
+				w.check(cmd)
+			case <-pollTicker.C:
+				current := w.modTimes()
+				var changed []string
+				for path, modTime := range current {
+					if !modTime.Equal(modTimes[path]) {
+						changed = append(changed, path)
+					}
+				}
+				modTimes = current
+				if len(changed) == 0 {
+					continue
+				}
+				slices.Sort(changed)
+				log.Info().Msgf("%v changed, checking the stacks", changed)
+				w.reload()
+				modTimes = w.modTimes()
+				w.check(cmd)
+			}
+		}
+	},
+}
+
+// Kinds of watch events.
+const (
+	watchAdded      = "added"
+	watchRemoved    = "removed"
+	watchVersion    = "version-changed"
+	watchRiskWindow = "risk-window"
+	watchEol        = "eol"
+	watchNewLatest  = "new-latest"
+	watchLtsMoved   = "lts-moved"
+	watchScore      = "score"
+)
+
+// watchEvent is a change of a stack between two checks.
+type watchEvent struct {
+	Time string `json:"time"`
+	App  string `json:"app"`
+	// Item is the name of the stack item, empty for score changes
+	Item    string `json:"item,omitempty"`
+	Kind    string `json:"kind"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+	Message string `json:"message"`
+}
+
+// watchComponent is the evaluation of a stack item kept between two checks.
+type watchComponent struct {
+	Version       string `json:"version"`
+	Status        string `json:"status"`
+	EolDate       string `json:"eol_date"`
+	InRiskWindow  bool   `json:"in_risk_window"`
+	LatestVersion string `json:"latest_version"`
+	LtsStrategy   string `json:"lts_strategy,omitempty"`
+	LatestLts     string `json:"latest_lts,omitempty"`
+}
+
+// watchSnapshot is the evaluation of a stack kept between two checks.
+type watchSnapshot struct {
+	Score      int                       `json:"score"`
+	Components map[string]watchComponent `json:"components"`
+}
+
+// watchState is the last evaluation of every watched app, keyed by app.
+type watchState map[string]watchSnapshot
+
+// watchStatePath returns the path to the file holding the watchState.
+func watchStatePath() (string, error) {
+	geolDir, err := utilities.GetGeolDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(geolDir, "watch-state.json"), nil
+}
+
+// stackFiles are stack files read again while geol runs, by watch and serve.
+type stackFiles struct {
+	files []string
+	env   string
+	// inputs are the last valid stacks read from files, in the same order
+	inputs []stackInput
+}
+
+// readStackFiles reads the stack files, and exits with an error when one of them is invalid or
+// when two of them describe the same app.
+func readStackFiles(files []string, env string) stackFiles {
+	s := stackFiles{files: files, env: env, inputs: make([]stackInput, len(files))}
+	for i, file := range files {
+		s.inputs[i] = readStackFile(file, env)
+	}
+	if err := checkDistinctApps(s.inputs); err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+	return s
+}
+
+// reload reads the stack files again. An invalid file keeps its previous stack, so that an
+// unfinished edit does not stop the watch or the server.
+func (s *stackFiles) reload() {
+	for i, file := range s.files {
+		input, err := loadStackFile(file, s.env)
+		if err != nil {
+			log.Error().Msgf("%s, keeping the previous version of the stack", err)
+			continue
+		}
+		s.inputs[i] = input
+	}
+}
+
+// stackWatcher checks the watched stack files and reports their changes.
+type stackWatcher struct {
+	stackFiles
+	jsonOutput bool
+	statePath  string
+	state      watchState
+}
+
+// modTimes returns the modification time of the stack files and of the files they extend.
+func (w *stackWatcher) modTimes() map[string]time.Time {
+	modTimes := map[string]time.Time{}
+	add := func(path string) {
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		} else {
+			modTimes[path] = time.Time{}
+		}
+	}
+	for i, file := range w.files {
+		add(file)
+		for _, location := range w.inputs[i].Locations {
+			if location.File != "" {
+				add(location.File)
+			}
+		}
+	}
+	return modTimes
+}
+
+// check evaluates the stacks as of now, reports their changes since the previous check, then
+// saves their evaluation. A failed refresh of the cache is logged, and the stacks are checked
+// with the cached data.
+func (w *stackWatcher) check(cmd *cobra.Command) {
+	if err := utilities.RefreshStaleCache(cmd); err != nil {
+		log.Warn().Err(err).Msg("Error refreshing the cache, checking with the cached data")
+	}
+	now := time.Now()
+	for _, input := range w.inputs {
+		result, err := evaluateStack(input, now)
+		if err != nil {
+			log.Error().Msgf("%s: %v", input.appKey(), err)
+			continue
+		}
+		current := snapshotStack(result)
+		previous, seen := w.state[input.appKey()]
+		w.state[input.appKey()] = current
+		if !seen {
+			log.Info().Msgf("%s: baseline recorded, stack score %d/100 with %d components", input.appKey(), current.Score, len(current.Components))
+			continue
+		}
+		events := diffSnapshots(input.appKey(), previous, current, input.Scoring.RiskThresholdDays, now)
+		if len(events) == 0 {
+			log.Debug().Msgf("%s: no change", input.appKey())
+		}
+		for _, event := range events {
+			w.print(event)
+		}
+	}
+
+	data, err := json.MarshalIndent(w.state, "", "  ")
+	if err == nil {
+		err = os.WriteFile(w.statePath, data, 0o644)
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("Error writing the watch state %s", w.statePath)
+	}
+}
+
+// print writes the event to stdout, as a JSON line with --json.
+func (w *stackWatcher) print(event watchEvent) {
+	if w.jsonOutput {
+		data, err := json.Marshal(event)
+		if err != nil {
+			log.Error().Err(err).Msg("Error generating JSON output")
+			return
+		}
+		fmt.Println(string(data))
+		return
+	}
+	style := lipgloss.NewStyle()
+	switch event.Kind {
+	case watchEol, watchRemoved:
+		style = style.Foreground(lipgloss.Color("196"))
+	case watchRiskWindow, watchLtsMoved:
+		style = style.Foreground(lipgloss.Color("208"))
+	case watchScore:
+		from, _ := strconv.Atoi(event.From)
+		to, _ := strconv.Atoi(event.To)
+		if to < from {
+			style = style.Foreground(lipgloss.Color("208"))
+		} else {
+			style = style.Foreground(lipgloss.Color("46"))
+		}
+	}
+	_, _ = lipgloss.Printf("%s %s: %s\n", event.Time, event.App, style.Render(event.Message))
+}
+
+// snapshotStack returns the part of an evaluation compared between two checks.
+func snapshotStack(result stackResult) watchSnapshot {
+	snapshot := watchSnapshot{Score: result.Score.Value, Components: map[string]watchComponent{}}
+	for _, r := range result.Rows {
+		days, err := strconv.Atoi(r.Days)
+		snapshot.Components[r.Software] = watchComponent{
+			Version:       r.Version,
+			Status:        r.Status,
+			EolDate:       r.EolDate,
+			InRiskWindow:  err == nil && days >= 0 && days <= result.Scoring.RiskThresholdDays,
+			LatestVersion: r.LatestVersion,
+			LtsStrategy:   r.LtsStrategy,
+			LatestLts:     r.LatestLts,
+		}
+	}
+	return snapshot
+}
+
+// diffSnapshots returns the changes between two evaluations of the app, sorted by item.
+func diffSnapshots(app string, previous, current watchSnapshot, riskThresholdDays int, now time.Time) []watchEvent {
+	var events []watchEvent
+	add := func(item, kind, from, to, message string) {
+		events = append(events, watchEvent{Time: now.Format(time.RFC3339), App: app, Item: item, Kind: kind, From: from, To: to, Message: message})
+	}
+
+	var names []string
+	for name := range previous.Components {
+		names = append(names, name)
+	}
+	for name := range current.Components {
+		if _, ok := previous.Components[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		before, wasThere := previous.Components[name]
+		after, isThere := current.Components[name]
+		switch {
+		case !isThere:
+			add(name, watchRemoved, before.Version, "", fmt.Sprintf("%s %s was removed from the stack", name, before.Version))
+			continue
+		case !wasThere:
+			add(name, watchAdded, "", after.Version, fmt.Sprintf("%s %s was added to the stack (%s)", name, after.Version, after.Status))
+			continue
+		}
+		if before.Version != after.Version {
+			add(name, watchVersion, before.Version, after.Version, fmt.Sprintf("%s changed from %s to %s (%s)", name, before.Version, after.Version, after.Status))
+		}
+		if before.Status != "EOL" && after.Status == "EOL" {
+			add(name, watchEol, before.Status, after.Status, fmt.Sprintf("%s %s is now past EOL (EOL: %s)", name, after.Version, after.EolDate))
+		} else if !before.InRiskWindow && after.InRiskWindow && after.Status != "EOL" {
+			add(name, watchRiskWindow, before.EolDate, after.EolDate, fmt.Sprintf("%s %s entered the %d-day risk window (EOL: %s)", name, after.Version, riskThresholdDays, after.EolDate))
+		}
+		if before.LatestVersion != after.LatestVersion && before.LatestVersion != "-" && after.LatestVersion != "-" {
+			add(name, watchNewLatest, before.LatestVersion, after.LatestVersion, fmt.Sprintf("%s %s is the new latest version (was %s, stack: %s)", name, after.LatestVersion, before.LatestVersion, after.Version))
+		}
+		if after.LtsStrategy == "latest" && before.LatestLts != "" && before.LatestLts != after.LatestLts {
+			add(name, watchLtsMoved, before.LatestLts, after.LatestLts, fmt.Sprintf("The latest LTS cycle of %s moved from %s to %s (stack: %s, lts_strategy: latest)", name, before.LatestLts, after.LatestLts, after.Version))
+		}
+	}
+
+	if previous.Score != current.Score {
+		add("", watchScore, strconv.Itoa(previous.Score), strconv.Itoa(current.Score),
+			fmt.Sprintf("Stack score changed from %d/100 to %d/100 (%s)", previous.Score, current.Score, scoreDelta(current.Score-previous.Score)))
+	}
+	return events
+}
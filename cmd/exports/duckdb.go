@@ -5,11 +5,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 
@@ -134,7 +134,9 @@ func (m model) View() tea.View {
 	return tea.NewView(content)
 }
 
-// fetchAllProductData retrieves all product information and details from the API in a single pass
+// fetchAllProductData retrieves all product information and details from the API in a single pass,
+// fetching up to utilities.Concurrency products in parallel. Each product is requested once, its
+// payload providing both the metadata and the releases.
 func fetchAllProductData(cmd *cobra.Command) (*productDataMap, error) {
 	// Get products from cache
 	productsPath, err := utilities.GetProductsPath()
@@ -153,40 +155,31 @@ func fetchAllProductData(cmd *cobra.Command) (*productDataMap, error) {
 		Products: make(map[string]*productData),
 	}
 
-	// Read API delay from environment variable
-	var apiDelay time.Duration
-	if delayStr := os.Getenv("GEOL_API_DELAY_MS"); delayStr != "" {
-		if delayMs, err := strconv.Atoi(delayStr); err == nil && delayMs > 0 {
-			apiDelay = time.Duration(delayMs) * time.Millisecond
-			log.Info().Msgf("GEOL_API_DELAY_MS: API delay set to %v ms between requests (export GEOL_API_DELAY_MS=50 to change, or 0 to disable)", apiDelay)
-		}
+	productNames := make([]string, 0, len(products.Products))
+	for name := range products.Products {
+		productNames = append(productNames, name)
 	}
+	sort.Strings(productNames)
 
 	isTTY := term.IsTerminal(int(os.Stdout.Fd()))
 
-	// processProduct fetches and stores all data for a single product, returning false on fatal error.
+	// Products that could not be fetched, even after retries, are skipped and reported at the end.
+	// mu guards allData and skipped, which are written by concurrent workers.
+	var skipped []string
+	var mu sync.Mutex
+	skip := func(productName string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		skipped = append(skipped, productName)
+		return false
+	}
+
+	// processProduct fetches and stores all data for a single product, returning false when it was skipped.
 	processProduct := func(productName string) bool {
-		url := utilities.APIUrl + "products/" + productName
-		resp, err := http.Get(url)
+		body, err := utilities.FetchProductBody(productName)
 		if err != nil {
 			log.Warn().Err(err).Msgf("Error requesting %s, skipping", productName)
-			return true
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		if cerr := resp.Body.Close(); cerr != nil {
-			log.Warn().Err(cerr).Msgf("Error closing HTTP body for %s", productName)
-		}
-		if err != nil {
-			log.Warn().Err(err).Msgf("Error reading response for %s, skipping", productName)
-			return true
-		}
-
-		if resp.StatusCode != 200 {
-			log.Error().Msgf("Client error for product %s: API returned status %d", productName, resp.StatusCode)
-			cleanupDuckDBFiles()
-			log.Fatal().Msg("Try to export GEOL_API_DELAY_MS=200 to add a delay between API requests and avoid overloading the API then retry")
-			return false
+			return skip(productName)
 		}
 
 		var apiResp struct {
@@ -205,15 +198,17 @@ func fetchAllProductData(cmd *cobra.Command) (*productDataMap, error) {
 
 		if err := json.Unmarshal(body, &apiResp); err != nil {
 			log.Warn().Err(err).Msgf("Error decoding JSON for %s, skipping", productName)
-			return true
+			return skip(productName)
 		}
 
-		prodData, err := product.FetchProductData(productName)
+		prodData, err := product.ParseProductData(productName, body)
 		if err != nil {
-			log.Warn().Err(err).Msgf("Error fetching product data for %s, skipping", productName)
-			return true
+			log.Warn().Err(err).Msgf("Error decoding product data for %s, skipping", productName)
+			return skip(productName)
 		}
 
+		mu.Lock()
+		defer mu.Unlock()
 		allData.Products[productName] = &productData{
 			Name:        apiResp.Result.Name,
 			Aliases:     apiResp.Result.Aliases,
@@ -240,42 +235,56 @@ func fetchAllProductData(cmd *cobra.Command) (*productDataMap, error) {
 
 		p := tea.NewProgram(m)
 
-		go func() {
-			for productName := range products.Products {
-				processProduct(productName)
-				p.Send(productProcessedMsg(productName))
-				if apiDelay > 0 {
-					time.Sleep(apiDelay)
-				}
-			}
-		}()
+		go utilities.RunConcurrently(len(productNames), func(i int) {
+			processProduct(productNames[i])
+			p.Send(productProcessedMsg(productNames[i]))
+		})
 
 		if _, err := p.Run(); err != nil {
 			log.Error().Err(err).Msg("Error running progress display")
 			return nil, err
 		}
 	} else {
-		// Non-interactive mode (no TTY): process sequentially with log output
-		total := len(products.Products)
-		i := 0
-		for productName := range products.Products {
-			i++
-			log.Info().Msgf("Fetching product data [%d/%d]: %s", i, total, productName)
-			processProduct(productName)
-			if apiDelay > 0 {
-				time.Sleep(apiDelay)
-			}
-		}
+		// Non-interactive mode (no TTY): process in parallel with log output
+		total := len(productNames)
+		var fetched atomic.Int32
+		utilities.RunConcurrently(total, func(i int) {
+			processProduct(productNames[i])
+			log.Info().Msgf("Fetched product data [%d/%d]: %s", fetched.Add(1), total, productNames[i])
+		})
 	}
 
+	if len(skipped) > 0 {
+		sort.Strings(skipped)
+		log.Error().Msgf("%d products could not be fetched and are missing from the export: %s", len(skipped), strings.Join(skipped, ", "))
+	}
 	log.Info().Msgf("Fetched data for %d products", len(allData.Products))
 	return allData, nil
 }
 
 // fetchAllCategories retrieves all categories from the API
 func fetchAllCategories() (map[string]utilities.Category, error) {
+	// In offline mode, rebuild the categories from the local cache
+	if utilities.Offline {
+		categoriesPath, err := utilities.GetCategoriesPath()
+		if err != nil {
+			log.Error().Err(err).Msg("Error retrieving categories path")
+			return nil, err
+		}
+		cached, err := utilities.GetCategoriesWithCacheRefresh(nil, categoriesPath)
+		if err != nil {
+			return nil, err
+		}
+		categories := make(map[string]utilities.Category)
+		for name, uri := range cached {
+			categories[name] = utilities.Category{Name: name, Uri: uri}
+		}
+		log.Info().Msgf("Read %d categories from the local cache", len(categories))
+		return categories, nil
+	}
+
 	// Fetch categories from API
-	resp, err := http.Get(utilities.APIUrl + "categories")
+	resp, err := utilities.GetAPIResponse(utilities.APIUrl + "categories")
 	if err != nil {
 		log.Error().Err(err).Msg("Error fetching categories")
 		return nil, err
@@ -286,12 +295,6 @@ func fetchAllCategories() (map[string]utilities.Category, error) {
 		}
 	}()
 
-	if resp.StatusCode != 200 {
-		log.Error().Msgf("Client error fetching categories: API returned status %d", resp.StatusCode)
-		cleanupDuckDBFiles()
-		log.Fatal().Msg("Try to export GEOL_API_DELAY_MS=200 to add a delay between API requests and avoid overloading the API then retry")
-	}
-
 	var apiResp struct {
 		Result []utilities.Category `json:"result"`
 	}
@@ -312,8 +315,27 @@ func fetchAllCategories() (map[string]utilities.Category, error) {
 
 // fetchAllTags retrieves all tags from the API
 func fetchAllTags() (map[string]utilities.Tag, error) {
+	// In offline mode, rebuild the tags from the local cache
+	if utilities.Offline {
+		tagsPath, err := utilities.GetTagsPath()
+		if err != nil {
+			log.Error().Err(err).Msg("Error retrieving tags path")
+			return nil, err
+		}
+		cached, err := utilities.GetTagsWithCacheRefresh(nil, tagsPath)
+		if err != nil {
+			return nil, err
+		}
+		tags := make(map[string]utilities.Tag)
+		for name, uri := range cached {
+			tags[name] = utilities.Tag{Name: name, Uri: uri}
+		}
+		log.Info().Msgf("Read %d tags from the local cache", len(tags))
+		return tags, nil
+	}
+
 	// Fetch tags from API
-	resp, err := http.Get(utilities.APIUrl + "tags")
+	resp, err := utilities.GetAPIResponse(utilities.APIUrl + "tags")
 	if err != nil {
 		log.Error().Err(err).Msg("Error fetching tags")
 		return nil, err
@@ -324,12 +346,6 @@ func fetchAllTags() (map[string]utilities.Tag, error) {
 		}
 	}()
 
-	if resp.StatusCode != 200 {
-		log.Error().Msgf("Client error fetching tags: API returned status %d", resp.StatusCode)
-		cleanupDuckDBFiles()
-		log.Fatal().Msg("Try to export GEOL_API_DELAY_MS=200 to add a delay between API requests and avoid overloading the API then retry")
-	}
-
 	var apiResp struct {
 		Result []utilities.Tag `json:"result"`
 	}
@@ -0,0 +1,24 @@
+package mirror
+
+import (
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+)
+
+// MirrorCmd represents the mirror command
+var MirrorCmd = &cobra.Command{
+	Use:     "mirror",
+	Aliases: []string{"m"},
+	Short:   "Share a local cache snapshot as an endoflife.date API mirror",
+	Long: `The mirror command shares a snapshot downloaded with 'geol cache refresh --full' over HTTP, in the same shape as the endoflife.date API.
+Point other geol instances to the mirror with --api-url, the GEOL_API_URL environment variable or the api_url setting of the configuration file.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cmd.Help(); err != nil {
+			log.Error().Err(err).Msg("Error displaying help")
+		}
+	},
+}
+
+func init() {
+	MirrorCmd.AddCommand(serveCmd)
+}
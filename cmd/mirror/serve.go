@@ -0,0 +1,269 @@
+package mirror
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opt-nc/geol/v2/utilities"
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+)
+
+// schemaVersion is the endoflife.date API schema version advertised by the mirror.
+const schemaVersion = "1.2.0"
+
+func init() {
+	serveCmd.Flags().String("addr", ":8080", "Address to listen on")
+	serveCmd.Flags().String("dir", "", "Snapshot directory (default: the geol directory in the user's config directory)")
+}
+
+// serveCmd represents the mirror serve command
+var serveCmd = &cobra.Command{
+	Use:     "serve",
+	Aliases: []string{"s"},
+	Short:   "Serve a cache snapshot over HTTP in the endoflife.date API shape",
+	Long: `Serve a snapshot downloaded with 'geol cache refresh --full' over HTTP.
+
+The following endpoints are available, mirroring the endoflife.date API:
+- /api/v1/products
+- /api/v1/products/{product}
+- /api/v1/products/{product}/releases/{release}
+- /api/v1/categories
+- /api/v1/categories/{category}
+- /api/v1/tags
+- /api/v1/tags/{tag}
+
+By default the snapshot is read from the geol directory in the user's config directory. Use --dir to serve a copy of that directory instead.`,
+	Example: `geol cache refresh --full
+geol mirror serve
+geol mirror serve --addr :9000 --dir /srv/geol-snapshot
+# then, on every CI runner
+geol --api-url http://mirror.internal:8080/api/v1/ check`,
+	Run: func(cmd *cobra.Command, args []string) {
+		addr, _ := cmd.Flags().GetString("addr")
+		dir, _ := cmd.Flags().GetString("dir")
+		if dir == "" {
+			geolDir, err := utilities.GetGeolDir()
+			if err != nil {
+				log.Fatal().Err(err).Msg("Error retrieving the geol config directory")
+			}
+			dir = geolDir
+		}
+		if _, err := os.Stat(filepath.Join(dir, utilities.ReleasesCacheDir)); err != nil {
+			log.Fatal().Msgf("No full cache snapshot found in %s, run 'geol cache refresh --full' first", dir)
+		}
+
+		m := snapshot{dir: dir}
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v1/products", m.handleProducts)
+		mux.HandleFunc("GET /api/v1/products/{product}", m.handleProduct)
+		mux.HandleFunc("GET /api/v1/products/{product}/releases/{release}", m.handleRelease)
+		mux.HandleFunc("GET /api/v1/categories", m.handleIndex("categories.json", "categories/"))
+		mux.HandleFunc("GET /api/v1/categories/{name}", m.handleFile(utilities.CategoriesCacheDir))
+		mux.HandleFunc("GET /api/v1/tags", m.handleIndex("tags.json", "tags/"))
+		mux.HandleFunc("GET /api/v1/tags/{name}", m.handleFile(utilities.TagsCacheDir))
+
+		server := &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		log.Info().Msgf("Serving the snapshot from %s on %s (API base path /api/v1/)", dir, addr)
+		if err := server.ListenAndServe(); err != nil {
+			log.Fatal().Err(err).Msg("Error running the mirror server")
+		}
+	},
+}
+
+// snapshot serves the files of a full cache directory.
+type snapshot struct {
+	dir string
+}
+
+// baseURL returns the API base URL of the mirror as seen by the client, with the scheme given by
+// a TLS-terminating proxy in X-Forwarded-Proto, if any.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ","); proto != "" {
+		scheme = strings.ToLower(strings.TrimSpace(proto))
+	}
+	return scheme + "://" + r.Host + "/api/v1/"
+}
+
+// handleProducts rebuilds the products list from products.json, enriched with the label,
+// category and tags of each cached release payload.
+func (s snapshot) handleProducts(w http.ResponseWriter, r *http.Request) {
+	data, err := os.ReadFile(filepath.Join(s.dir, "products.json"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "products.json not found in the snapshot")
+		return
+	}
+	var products utilities.ProductsFile
+	if err := json.Unmarshal(data, &products); err != nil {
+		writeError(w, http.StatusInternalServerError, "invalid products.json in the snapshot")
+		return
+	}
+
+	type productSummary struct {
+		Name     string   `json:"name"`
+		Label    string   `json:"label"`
+		Aliases  []string `json:"aliases"`
+		Category string   `json:"category"`
+		Tags     []string `json:"tags"`
+		Uri      string   `json:"uri"`
+	}
+	names := make([]string, 0, len(products.Products))
+	for name := range products.Products {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]productSummary, 0, len(names))
+	for _, name := range names {
+		// The first alias stored in products.json is the product name itself
+		aliases := []string{}
+		if all := products.Products[name]; len(all) > 1 {
+			aliases = all[1:]
+		}
+		summary := productSummary{Name: name, Label: name, Aliases: aliases, Tags: []string{}, Uri: baseURL(r) + "products/" + name}
+		if body, err := os.ReadFile(filepath.Join(s.dir, utilities.ReleasesCacheDir, name+".json")); err == nil {
+			var payload struct {
+				Result struct {
+					Label    string   `json:"label"`
+					Category string   `json:"category"`
+					Tags     []string `json:"tags"`
+				} `json:"result"`
+			}
+			if json.Unmarshal(body, &payload) == nil {
+				if payload.Result.Label != "" {
+					summary.Label = payload.Result.Label
+				}
+				summary.Category = payload.Result.Category
+				if payload.Result.Tags != nil {
+					summary.Tags = payload.Result.Tags
+				}
+			}
+		}
+		result = append(result, summary)
+	}
+	writeJSON(w, map[string]any{"schema_version": schemaVersion, "total": len(result), "result": result})
+}
+
+// handleProduct serves the cached release payload of a product.
+func (s snapshot) handleProduct(w http.ResponseWriter, r *http.Request) {
+	s.serveFile(w, utilities.ReleasesCacheDir, r.PathValue("product"))
+}
+
+// handleRelease extracts a single release cycle from the cached payload of a product.
+func (s snapshot) handleRelease(w http.ResponseWriter, r *http.Request) {
+	product, release := r.PathValue("product"), r.PathValue("release")
+	body, ok := s.readFile(w, utilities.ReleasesCacheDir, product)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Result struct {
+			Releases []json.RawMessage `json:"releases"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		writeError(w, http.StatusInternalServerError, "invalid payload for product "+product)
+		return
+	}
+	for _, raw := range payload.Result.Releases {
+		var rel struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(raw, &rel) == nil && rel.Name == release {
+			writeJSON(w, map[string]any{"schema_version": schemaVersion, "result": raw})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "release "+release+" not found for product "+product)
+}
+
+// handleIndex rebuilds a categories/tags list from its name -> uri cache file, with URIs
+// pointing to the mirror itself.
+func (s snapshot) handleIndex(file, endpoint string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := os.ReadFile(filepath.Join(s.dir, file))
+		if err != nil {
+			writeError(w, http.StatusNotFound, file+" not found in the snapshot")
+			return
+		}
+		var index map[string]string
+		if err := json.Unmarshal(data, &index); err != nil {
+			writeError(w, http.StatusInternalServerError, "invalid "+file+" in the snapshot")
+			return
+		}
+		names := make([]string, 0, len(index))
+		for name := range index {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		type entry struct {
+			Name string `json:"name"`
+			Uri  string `json:"uri"`
+		}
+		result := make([]entry, 0, len(names))
+		for _, name := range names {
+			result = append(result, entry{Name: name, Uri: baseURL(r) + endpoint + name})
+		}
+		writeJSON(w, map[string]any{"schema_version": schemaVersion, "total": len(result), "result": result})
+	}
+}
+
+// handleFile serves the cached payload named by the {name} path value from a cache sub-directory.
+func (s snapshot) handleFile(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.serveFile(w, kind, r.PathValue("name"))
+	}
+}
+
+func (s snapshot) serveFile(w http.ResponseWriter, kind, name string) {
+	if body, ok := s.readFile(w, kind, name); ok {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(body); err != nil {
+			log.Warn().Err(err).Msg("Error writing response")
+		}
+	}
+}
+
+// readFile reads kind/name.json from the snapshot, answering 404 when it is missing.
+func (s snapshot) readFile(w http.ResponseWriter, kind, name string) ([]byte, bool) {
+	// Reject path separators so a request cannot escape the snapshot directory
+	if name == "" || name != filepath.Base(name) || name == ".." {
+		writeError(w, http.StatusBadRequest, "invalid name")
+		return nil, false
+	}
+	body, err := os.ReadFile(filepath.Join(s.dir, kind, name+".json"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, kind+"/"+name+" not found in the snapshot")
+		return nil, false
+	}
+	return body, true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warn().Err(err).Msg("Error writing response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": message}); err != nil {
+		log.Warn().Err(err).Msg("Error writing response")
+	}
+}
@@ -72,11 +72,18 @@ var describeCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		// Product descriptions come from the endoflife.date repository, not from the API,
+		// so they are not part of the offline cache.
+		if utilities.Offline {
+			log.Error().Msgf("The description of %s is not available in offline mode, run the command again without --offline", mainName)
+			os.Exit(1)
+		}
+
 		// Build the markdown URL
-		mdUrl := "https://raw.githubusercontent.com/endoflife-date/endoflife.date/refs/heads/master/products/" + mainName + ".md"
+		mdUrl := utilities.DescribeUrl + mainName + ".md"
 
 		// Retrieve the Markdown content
-		resp, err := http.Get(mdUrl)
+		resp, err := utilities.HTTPGet(mdUrl)
 		if err != nil {
 			log.Error().Err(err).Msg("Error fetching markdown of the product " + mainName)
 			os.Exit(1)
@@ -125,25 +132,11 @@ var describeCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		url := utilities.APIUrl + "products/" + mainName
-		resp, err = http.Get(url)
+		body, err := utilities.FetchProductBody(mainName)
 		if err != nil {
 			log.Error().Err(err).Msgf("Error requesting %s", mainName)
 			os.Exit(1)
 		}
-		body, err := io.ReadAll(resp.Body)
-		if cerr := resp.Body.Close(); cerr != nil {
-			log.Error().Err(cerr).Msgf("Error closing HTTP body for %s", mainName)
-			os.Exit(1)
-		}
-		if err != nil {
-			log.Error().Err(err).Msgf("Error reading response for %s", mainName)
-			os.Exit(1)
-		}
-		if resp.StatusCode != 200 {
-			log.Error().Msgf("Product %s not found on the API.", mainName)
-			os.Exit(1)
-		}
 
 		var apiResp ApiRestDescribe
 		if err := json.Unmarshal(body, &apiResp); err != nil {
@@ -176,7 +169,7 @@ var describeCmd = &cobra.Command{
 		desc += "\n\nYou can subscribe to the iCalendar feed at `webcal://endoflife.date/calendar/" + mainName + ".ics`"
 
 		// Add A JSON version of this page is available at /api/v1/products/neo4j/
-		desc += "\n\n### JSON Version\n\nA JSON version of this page is available at `https://endoflife.date/api/v1/products/" + mainName + "`"
+		desc += "\n\n### JSON Version\n\nA JSON version of this page is available at `" + utilities.APIUrl + "products/" + mainName + "`"
 
 		// Print a product title as in extended: # ProductName, with color and background
 		styledTitle := lipgloss.NewStyle().
@@ -3,8 +3,6 @@ package product
 import (
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -20,6 +18,8 @@ import (
 func init() {
 	extendedCmd.Flags().IntP("number", "n", 10, "Number of latest versions to display (default: 10, 0 to show all)")
 	extendedCmd.Flags().Bool("json", false, "Output results in JSON format")
+	extendedCmd.Flags().String("format", "table", "Output format: table, json, ics")
+	extendedCmd.Flags().IntSlice("alarms", utilities.DefaultAlarmDays, "Reminders of the --format ics events, in days before each date")
 }
 
 // extendedCmd represents the extended command
@@ -33,18 +33,31 @@ geol product extended quarkus -n 15
 # Redirect output to a markdown file
 geol product extended quarkus > quarkus-eol.md
 # Export as JSON
-geol product extended golang --json`,
+geol product extended golang --json
+# Export the EOL and end of active support dates as an iCalendar file
+geol product extended nodejs --format ics > nodejs-eol.ics`,
 	Short: "Display extended release information for specified products (latest 10 versions by default).",
 	Long:  `Retrieve and display detailed release data for one or more products, including cycle, release dates, support periods, and end-of-life information. By default, the latest 10 versions are shown for each product; use the --number flag to display the latest n versions instead. Results are formatted in a styled table for easy reading. Products must exist in the local cache or be available via the API.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		numberFlag, _ := cmd.Flags().GetInt("number")
 		jsonFlag, _ := cmd.Flags().GetBool("json")
+		format, _ := cmd.Flags().GetString("format")
+		if jsonFlag {
+			format = "json"
+		}
+		if format != "table" && format != "json" && format != "ics" {
+			log.Fatal().Msgf("Unknown --format %q (expected one of: table, json, ics)", format)
+		}
 		cliColorForced, _ := strconv.ParseBool(os.Getenv("CLICOLOR_FORCE"))
 		mdFlag := !term.IsTerminal(os.Stdout.Fd()) && !cliColorForced // detect if output is not a terminal
 
 		if numberFlag < 0 {
 			log.Fatal().Msg("The number of rows must be zero or positive.")
 		}
+		alarms, _ := cmd.Flags().GetIntSlice("alarms")
+		if err := utilities.ValidateAlarmDays(alarms); err != nil {
+			log.Fatal().Msg(err.Error())
+		}
 
 		if len(args) == 0 {
 			log.Fatal().Msg("Please specify at least one product.")
@@ -62,7 +75,7 @@ geol product extended golang --json`,
 			log.Fatal().Err(err).Msg("Error retrieving products from cache")
 		}
 
-		var allProducts []ProductReleases
+		var names []string
 
 		for _, prod := range args {
 			found := false
@@ -88,11 +101,19 @@ geol product extended golang --json`,
 				continue // product not found in cache
 			}
 
-			prodData, err := FetchProductData(prod)
+			names = append(names, prod)
+		}
+
+		// Fetch all products in parallel, keeping the order of the arguments
+		allProducts := make([]ProductReleases, len(names))
+		errs := make([]error, len(names))
+		utilities.RunConcurrently(len(names), func(i int) {
+			allProducts[i], errs[i] = FetchProductData(names[i])
+		})
+		for _, err := range errs {
 			if err != nil {
 				log.Fatal().Err(err).Msg("Error fetching product data")
 			}
-			allProducts = append(allProducts, prodData)
 		}
 
 		if len(allProducts) == 0 {
@@ -100,11 +121,19 @@ geol product extended golang --json`,
 		}
 
 		// JSON output
-		if jsonFlag {
+		if format == "json" {
 			renderProductsJSON(allProducts, numberFlag)
 			return
 		}
 
+		// iCalendar output
+		if format == "ics" {
+			if err := renderProductsICS(allProducts, numberFlag, alarms); err != nil {
+				log.Fatal().Err(err).Msg("Error generating iCalendar output")
+			}
+			return
+		}
+
 		// Render tables for all products
 		for i, prod := range allProducts {
 			renderProductTable(prod, numberFlag, mdFlag, i == 0)
@@ -130,6 +159,39 @@ func renderProductsJSON(allProducts []ProductReleases, numberFlag int) {
 	fmt.Println(string(output))
 }
 
+// renderProductsICS outputs the EOL and end of active support dates of the releases as an
+// iCalendar file, one event per date, with UIDs made of the product, cycle and kind of date.
+func renderProductsICS(allProducts []ProductReleases, numberFlag int, alarms []int) error {
+	var events []utilities.CalendarEvent
+	var names []string
+	for _, prod := range allProducts {
+		names = append(names, prod.Name)
+		displayCount := numberFlag
+		if displayCount == 0 || displayCount > len(prod.Releases) {
+			displayCount = len(prod.Releases)
+		}
+		for _, r := range prod.Releases[:displayCount] {
+			if r.EoasFrom != "" {
+				events = append(events, utilities.CalendarEvent{
+					UID:         utilities.CalendarUID(prod.Name, r.Name, "eoas"),
+					Date:        r.EoasFrom,
+					Summary:     fmt.Sprintf("End of active support: %s %s", prod.Name, r.Name),
+					Description: fmt.Sprintf("%s %s reaches its end of active support (source: endoflife.date).", prod.Name, r.Name),
+				})
+			}
+			if r.EolFrom != "" {
+				events = append(events, utilities.CalendarEvent{
+					UID:         utilities.CalendarUID(prod.Name, r.Name, "eol"),
+					Date:        r.EolFrom,
+					Summary:     fmt.Sprintf("EOL: %s %s", prod.Name, r.Name),
+					Description: fmt.Sprintf("%s %s reaches its end of life (source: endoflife.date).", prod.Name, r.Name),
+				})
+			}
+		}
+	}
+	return utilities.WriteICS(os.Stdout, "geol: "+strings.Join(names, ", "), events, alarms)
+}
+
 // renderProductTable displays a formatted table for a single product's release information
 func renderProductTable(prod ProductReleases, numberFlag int, mdFlag bool, isFirst bool) {
 	// Print as a title "# Products" for the first product
@@ -309,22 +371,16 @@ func renderProductTable(prod ProductReleases, numberFlag int, mdFlag bool, isFir
 
 // FetchProductData retrieves product release data from the API
 func FetchProductData(productName string) (ProductReleases, error) {
-	url := utilities.APIUrl + "products/" + productName
-	resp, err := http.Get(url)
+	body, err := utilities.FetchProductBody(productName)
 	if err != nil {
 		return ProductReleases{}, fmt.Errorf("error requesting %s: %w", productName, err)
 	}
-	body, err := io.ReadAll(resp.Body)
-	if cerr := resp.Body.Close(); cerr != nil {
-		return ProductReleases{}, fmt.Errorf("error closing HTTP body for %s: %w", productName, cerr)
-	}
-	if err != nil {
-		return ProductReleases{}, fmt.Errorf("error reading response for %s: %w", productName, err)
-	}
-	if resp.StatusCode != 200 {
-		return ProductReleases{}, fmt.Errorf("product %s not found on the API", productName)
-	}
+	return ParseProductData(productName, body)
+}
 
+// ParseProductData decodes a products/{name} API payload into its release data, so that callers
+// which already hold the payload do not have to request it again.
+func ParseProductData(productName string, body []byte) (ProductReleases, error) {
 	var apiResp ApiRespExtended
 	if err := json.Unmarshal(body, &apiResp); err != nil {
 		return ProductReleases{}, fmt.Errorf("error decoding JSON for %s: %w", productName, err)
@@ -4,8 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 
 	"charm.land/glamour/v2"
@@ -46,7 +44,7 @@ geol product describe nodejs`,
 			log.Fatal().Err(err).Msg("Error retrieving products from cache")
 		}
 
-		var results []productResult
+		var names []string
 
 		for _, prod := range args {
 			found := false
@@ -72,22 +70,23 @@ geol product describe nodejs`,
 				continue // product not found in cache
 			}
 
-			// API request for this product
-			url := utilities.APIUrl + "products/" + prod
-			resp, err := http.Get(url)
+			names = append(names, prod)
+		}
+
+		// API requests for all products in parallel, keeping the order of the arguments
+		bodies := make([][]byte, len(names))
+		errs := make([]error, len(names))
+		utilities.RunConcurrently(len(names), func(i int) {
+			bodies[i], errs[i] = utilities.FetchProductBody(names[i])
+		})
+
+		var results []productResult
+
+		for i, prod := range names {
+			body, err := bodies[i], errs[i]
 			if err != nil {
 				log.Fatal().Err(err).Msgf("Error requesting %s", prod)
 			}
-			body, err := io.ReadAll(resp.Body)
-			if cerr := resp.Body.Close(); cerr != nil {
-				log.Fatal().Err(cerr).Msgf("Error closing HTTP body for %s", prod)
-			}
-			if err != nil {
-				log.Fatal().Err(err).Msgf("Error reading response for %s", prod)
-			}
-			if resp.StatusCode != 200 {
-				log.Fatal().Msgf("Product %s not found on the API", prod)
-			}
 
 			// JSON decoding
 			var apiResp struct {
@@ -5,9 +5,6 @@ import (
 	"fmt"
 	"os"
 
-	"cuelang.org/go/cue"
-	"cuelang.org/go/cue/cuecontext"
-	"cuelang.org/go/encoding/yaml"
 	"github.com/charmbracelet/fang"
 	"github.com/phuslu/log"
 	"github.com/spf13/cobra"
@@ -17,6 +14,7 @@ import (
 	"github.com/opt-nc/geol/v2/cmd/ci_github"
 	"github.com/opt-nc/geol/v2/cmd/exports"
 	"github.com/opt-nc/geol/v2/cmd/list"
+	"github.com/opt-nc/geol/v2/cmd/mirror"
 	"github.com/opt-nc/geol/v2/cmd/product"
 	"github.com/opt-nc/geol/v2/utilities"
 )
@@ -29,6 +27,30 @@ var rootCmd = &cobra.Command{
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		logLevel, _ := cmd.Flags().GetString("log-level")
 		utilities.InitLogger(logLevel)
+		utilities.Offline, _ = cmd.Flags().GetBool("offline")
+		utilities.Concurrency, _ = cmd.Flags().GetInt("concurrency")
+		if utilities.Concurrency < 1 {
+			log.Fatal().Msg("--concurrency must be at least 1")
+		}
+		config, err := utilities.LoadConfig()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error loading the geol configuration file")
+		}
+		apiURL, _ := cmd.Flags().GetString("api-url")
+		utilities.InitDataSource(config, apiURL)
+		maxAge, _ := cmd.Flags().GetDuration("max-age")
+		if err := utilities.InitCacheMaxAge(config, maxAge, cmd.Flags().Changed("max-age")); err != nil {
+			log.Fatal().Err(err).Msg("Error configuring the cache max age")
+		}
+		if err := utilities.InitHTTPClient(config.HTTP); err != nil {
+			log.Fatal().Err(err).Msg("Error configuring the HTTP client")
+		}
+		if err := utilities.InitScoring(config); err != nil {
+			log.Fatal().Err(err).Msg("Error configuring the scoring model")
+		}
+		if err := utilities.InitNotify(config); err != nil {
+			log.Fatal().Err(err).Msg("Error configuring the notifications")
+		}
 		checkGeolFile()
 	},
 }
@@ -58,46 +80,27 @@ func checkGeolFile() {
 }
 
 func validateWithCue(yamlFile string) error {
-	ctx := cuecontext.New()
-
-	// Load the CUE schema
-	cueSchemaData, err := os.ReadFile("geol_stack.cue")
+	data, err := os.ReadFile(yamlFile)
 	if err != nil {
-		return fmt.Errorf("failed to read geol_stack.cue: %w", err)
-	}
-
-	cueSchema := ctx.CompileBytes(cueSchemaData)
-	if cueSchema.Err() != nil {
-		return fmt.Errorf("CUE schema compilation error: %w", cueSchema.Err())
+		return err
 	}
-
-	// Convert YAML to CUE value
-	yamlExpr, err := yaml.Extract(yamlFile, nil)
-	if err != nil {
-		return fmt.Errorf("YAML extraction error: %w", err)
-	}
-
-	yamlValue := ctx.BuildFile(yamlExpr)
-	if yamlValue.Err() != nil {
-		return fmt.Errorf("value construction error: %w", yamlValue.Err())
-	}
-
-	// Unify and validate
-	unified := cueSchema.Unify(yamlValue)
-	if err := unified.Validate(cue.Concrete(true)); err != nil {
-		return fmt.Errorf("validation failed: %w", err)
-	}
-
-	return nil
+	return utilities.ValidateStack(yamlFile, data)
 }
 
 func init() {
 	rootCmd.AddCommand(cache.CacheCmd)
 	rootCmd.AddCommand(check.CheckCmd)
+	rootCmd.AddCommand(check.WatchCmd)
+	rootCmd.AddCommand(check.ServeCmd)
 	rootCmd.AddCommand(ci_github.CiGithubCmd)
 	rootCmd.AddCommand(product.ProductCmd)
 	rootCmd.AddCommand(list.ListCmd)
+	rootCmd.AddCommand(mirror.MirrorCmd)
 	rootCmd.AddCommand(exports.ExportCmd)
 
 	rootCmd.PersistentFlags().StringP("log-level", "l", "info", "Logging level, default info (debug, info, warn, error)")
+	rootCmd.PersistentFlags().String("api-url", "", "Base URL of the endoflife.date API or of a mirror (env GEOL_API_URL, config api_url)")
+	rootCmd.PersistentFlags().Int("concurrency", 8, "Maximum number of products fetched in parallel")
+	rootCmd.PersistentFlags().Duration("max-age", utilities.DefaultCacheMaxAge, "Age after which the local cache is refreshed (env GEOL_CACHE_MAX_AGE, config cache_max_age)")
+	rootCmd.PersistentFlags().Bool("offline", false, "Read data from the local cache only, never from the network (requires 'geol cache refresh --full')")
 }
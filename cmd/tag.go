@@ -6,8 +6,6 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 
 	"charm.land/lipgloss/v2"
 	"charm.land/lipgloss/v2/tree"
@@ -48,22 +46,10 @@ geol tag canonical`,
 			log.Fatal().Msgf("Tag '%s' not found in cache", tag)
 		}
 
-		url := utilities.APIUrl + "tags/" + tag
-		resp, err := http.Get(url)
+		body, err := utilities.FetchTagBody(tag)
 		if err != nil {
 			log.Fatal().Err(err).Msgf("Error requesting tag '%s'", tag)
 		}
-		body, err := io.ReadAll(resp.Body)
-		closeErr := resp.Body.Close()
-		if err != nil {
-			log.Fatal().Err(err).Msgf("Error reading response for tag '%s'", tag)
-		}
-		if closeErr != nil {
-			log.Fatal().Err(closeErr).Msgf("Error closing response body for tag '%s'", tag)
-		}
-		if resp.StatusCode != 200 {
-			log.Fatal().Msgf("Tag '%s' not found on the API", tag)
-		}
 
 		var apiResp struct {
 			Result []struct {
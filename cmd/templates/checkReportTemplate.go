@@ -0,0 +1,10 @@
+package templates
+
+import (
+	_ "embed"
+)
+
+// CheckReportTemplate is the html/template of the report written by 'geol check report'.
+//
+//go:embed checkReportTemplate.html
+var CheckReportTemplate string
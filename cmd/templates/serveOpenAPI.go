@@ -0,0 +1,10 @@
+package templates
+
+import (
+	_ "embed"
+)
+
+// ServeOpenAPI is the OpenAPI description of the REST API of 'geol serve'.
+//
+//go:embed serveOpenAPI.json
+var ServeOpenAPI string
@@ -19,6 +19,10 @@ var versionCmd = &cobra.Command{
 
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println(utilities.Version)
+		if utilities.Offline {
+			log.Info().Msg("Offline mode, skipping the latest geol version check")
+			return
+		}
 		log.Info().Msg("Checking the latest geol version...")
 		latestVersion := utilities.GetLatestVersionFromGitHub()
 		if latestVersion == "" {
@@ -11,6 +11,12 @@ app_name?: string
 // app_id: an optional identifier for the application
 app_id?: string
 
+// extends: an optional base stack file, relative to this file.
+// The base stack items are inherited, and an item of this file with
+// the same name replaces the inherited one. The merged stack is
+// validated against this schema.
+extends?: string
+
 stack: [...{
     // name: the name of the product as you want
     // it to appear in the report. For example,
@@ -57,4 +63,99 @@ stack: [...{
     // instead of a check failure, giving teams time to migrate.
     // Example: lts_grace_days: 30 means you have 30 days to upgrade after a new LTS drops.
     lts_grace_days?: int & >=0
+
+    // weight: optional weight of this product in the stack score average.
+    // Defaults to 1. Set a higher weight on critical components so that they count more.
+    weight?: number & >0
+
+    // waiver: optional, time-boxed acceptance of the risks of this product
+    // (e.g. a vendor support contract or an ongoing migration). Until it expires,
+    // the product is still checked but its violations are warnings that do not
+    // fail strict mode. Once expired, the waiver itself is a violation.
+    waiver?: #Waiver
+}]
+
+// environments: optional overlays, selected with 'geol check --env <name>'.
+// An overlay item refers to a stack item by name and overrides only its
+// version and policy fields, so that shared items are declared once.
+// Overlays of the base file (see extends) apply first.
+environments?: [string]: {
+    stack: [...{
+        name: string
+        version?: string
+        skip?: bool
+        "always-latest"?: bool
+        manual_eol?: string
+        lts_strategy?: "any" | "latest"
+        lts_grace_days?: int & >=0
+        waiver?: #Waiver
+    }]
+}
+
+// policies: optional rules checked on every stack item they select.
+// Selectors: category and tag (from endoflife.date) and id_eol; a rule
+// without selectors applies to every item. Conditions:
+// - min_days_to_eol: the minimum number of days left before EOL
+// - max_majors_behind: the maximum number of major versions released since the item's one
+// - require_lts: the version must be an active LTS release
+// A broken rule of severity "error" fails 'geol check --strict', a "warning" is only reported.
+policies?: [...{
+    id: string
+    description?: string
+    severity: "error" | "warning"
+    category?: string
+    tag?: string
+    id_eol?: [...string]
+    min_days_to_eol?: int
+    max_majors_behind?: int & >=0
+    require_lts?: bool
+}]
+
+// scoring: optional override of the debt scoring model, also settable in the
+// geol configuration file. Unset fields keep the configured (or default) values.
+// - risk_threshold_days: days before EOL at which a component is nearing EOL (default 180)
+// - tiers: the debt score (0-100) of each situation of a component
+// - bands: the lowest stack scores of the healthy and needs attention bands
+scoring?: {
+    risk_threshold_days?: int & >0
+    tiers?: {
+        past_eol?:               #Score
+        nearing_eol?:            #Score
+        nearing_eol_lts?:        #Score
+        nearing_eol_latest_lts?: #Score
+        major_lag?:              #Score
+        major_lag_lts?:          #Score
+        major_lag_latest_lts?:   #Score
+        minor_lag?:              #Score
+        latest?:                 #Score
+    }
+    bands?: {
+        healthy?:         #Score
+        needs_attention?: #Score
+    }
+}
+
+#Score: int & >=0 & <=100
+
+#Waiver: {
+    reason:      string
+    approved_by: string
+    // expires: the last day of the waiver (YYYY-MM-DD)
+    expires:     =~"^[0-9]{4}-[0-9]{2}-[0-9]{2}$"
+}
+
+// notify: optional webhooks notified with a summary after 'geol check', on top of
+// those of the geol configuration file. The notify: section of a base file (see
+// extends) is inherited unless this file defines its own.
+// - format: json (default), slack, teams, or template with a Go template
+// - on: always (default), violations, new-violations (since the previous run), or
+//   score-drop (by at least min_score_drop, 1 by default)
+// The url and headers may reference environment variables, e.g. ${SLACK_WEBHOOK_URL}.
+notify?: [...{
+    url:             string
+    format?:         "json" | "slack" | "teams" | "template"
+    template?:       string
+    headers?:        [string]: string
+    on?:             "always" | "violations" | "new-violations" | "score-drop"
+    min_score_drop?: int & >0
 }]
@@ -1,6 +1,7 @@
 package main
 
 import (
+	_ "embed"
 	"runtime"
 	"runtime/debug"
 
@@ -8,6 +9,9 @@ import (
 	"github.com/opt-nc/geol/v2/utilities"
 )
 
+//go:embed geol_stack.cue
+var stackSchema string
+
 // Variables injected at build time with ldflags
 var (
 	commit       = "none"
@@ -35,5 +39,6 @@ func main() {
 	utilities.GoVersion = goVersion
 	utilities.PlatformOs = platformOs
 	utilities.PlatformArch = platformArch
+	utilities.StackSchema = stackSchema
 	cmd.Execute()
 }
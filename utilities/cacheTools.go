@@ -0,0 +1,258 @@
+package utilities
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheMaxAge is the age after which the local cache is refreshed when nothing overrides it.
+const DefaultCacheMaxAge = 24 * time.Hour
+
+// CacheMaxAge is the age after which the local cache is refreshed (see InitCacheMaxAge).
+var CacheMaxAge = DefaultCacheMaxAge
+
+// InitCacheMaxAge sets CacheMaxAge from the --max-age flag when set, then the GEOL_CACHE_MAX_AGE
+// environment variable, then the cache_max_age setting of the configuration file.
+func InitCacheMaxAge(config Config, flagValue time.Duration, flagSet bool) error {
+	maxAge := DefaultCacheMaxAge
+	if config.CacheMaxAge > 0 {
+		maxAge = config.CacheMaxAge
+	}
+	if env := os.Getenv("GEOL_CACHE_MAX_AGE"); env != "" {
+		d, err := time.ParseDuration(env)
+		if err != nil {
+			return fmt.Errorf("invalid GEOL_CACHE_MAX_AGE %q: %w", env, err)
+		}
+		maxAge = d
+	}
+	if flagSet {
+		maxAge = flagValue
+	}
+	if maxAge <= 0 {
+		return fmt.Errorf("the cache max age must be positive, got %v", maxAge)
+	}
+	CacheMaxAge = maxAge
+	return nil
+}
+
+// cacheEntry holds the HTTP validators of a cached API resource, stored in geol/validators.json.
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	CheckedAt    time.Time `json:"checked_at"`
+	ChangedAt    time.Time `json:"changed_at"`
+}
+
+// cacheValidators is the in-memory copy of geol/validators.json, keyed by API resource
+// (e.g. "products" or "products/go"), loaded on first use.
+var cacheValidators = struct {
+	sync.Mutex
+	loaded  bool
+	entries map[string]cacheEntry
+}{}
+
+// getValidatorsPath returns the path to the validators.json file in the geol config directory.
+func getValidatorsPath() (string, error) {
+	geolDir, err := GetGeolDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(geolDir, "validators.json"), nil
+}
+
+// loadCacheValidators reads validators.json once. A missing or unreadable file starts empty,
+// which only costs a full download on the next refresh. Callers hold the lock.
+func loadCacheValidators() {
+	if cacheValidators.loaded {
+		return
+	}
+	cacheValidators.loaded = true
+	cacheValidators.entries = map[string]cacheEntry{}
+	path, err := getValidatorsPath()
+	if err != nil {
+		return
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &cacheValidators.entries)
+	}
+}
+
+func getCacheEntry(resource string) (cacheEntry, bool) {
+	cacheValidators.Lock()
+	defer cacheValidators.Unlock()
+	loadCacheValidators()
+	entry, ok := cacheValidators.entries[resource]
+	return entry, ok
+}
+
+func setCacheEntry(resource string, entry cacheEntry) {
+	cacheValidators.Lock()
+	defer cacheValidators.Unlock()
+	loadCacheValidators()
+	cacheValidators.entries[resource] = entry
+}
+
+// saveCacheValidators writes the in-memory validators back to validators.json.
+func saveCacheValidators() error {
+	cacheValidators.Lock()
+	defer cacheValidators.Unlock()
+	loadCacheValidators()
+	path, err := getValidatorsPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cacheValidators.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// RemoveCacheValidators deletes validators.json, so that the next refresh downloads everything.
+func RemoveCacheValidators() error {
+	cacheValidators.Lock()
+	defer cacheValidators.Unlock()
+	cacheValidators.loaded = false
+	path, err := getValidatorsPath()
+	if err != nil {
+		return err
+	}
+	return RemoveFileIfExists(path)
+}
+
+// fetchIfModified requests APIUrl+resource, sending the validators stored for it when its cached
+// copy at cachedPath still exists. It returns changed=false with no body when the server answers
+// 304 Not Modified, in which case the cached file is touched so that its age restarts.
+func fetchIfModified(resource, cachedPath string) (body []byte, changed bool, err error) {
+	if Offline {
+		return nil, false, fmt.Errorf("offline mode, refusing to request %s", APIUrl+resource)
+	}
+	url := APIUrl + resource
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	previous, ok := getCacheEntry(resource)
+	if _, statErr := os.Stat(cachedPath); ok && statErr == nil && previous.URL == url {
+		if previous.ETag != "" {
+			req.Header.Set("If-None-Match", previous.ETag)
+		}
+		if previous.LastModified != "" {
+			req.Header.Set("If-Modified-Since", previous.LastModified)
+		}
+	}
+
+	resp, err := HTTPDo(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("HTTP request error: %w", err)
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	now := time.Now()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		previous.CheckedAt = now
+		setCacheEntry(resource, previous)
+		if err := os.Chtimes(cachedPath, now, now); err != nil {
+			return nil, false, err
+		}
+		return nil, false, nil
+	case http.StatusOK:
+	default:
+		return nil, false, &HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading response for %s: %w", resource, err)
+	}
+	setCacheEntry(resource, cacheEntry{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		CheckedAt:    now,
+		ChangedAt:    now,
+	})
+	return body, true, nil
+}
+
+// ResourceStatus describes the freshness of one cached API resource.
+type ResourceStatus struct {
+	Resource string
+	Path     string
+	// CheckedAt is the last time the resource was downloaded or confirmed unchanged by the server.
+	CheckedAt time.Time
+	// ChangedAt is the last time the server returned new content, zero when unknown.
+	ChangedAt time.Time
+	// Conditional is true when validators (ETag or Last-Modified) are stored for the resource.
+	Conditional bool
+	Fresh       bool
+}
+
+// GetCacheStatus returns the freshness of every cached resource: the products, tags and
+// categories lists first, then the payloads of the full cache sorted by resource.
+func GetCacheStatus() ([]ResourceStatus, error) {
+	geolDir, err := GetGeolDir()
+	if err != nil {
+		return nil, err
+	}
+	var statuses []ResourceStatus
+	for _, resource := range []string{"products", "tags", "categories"} {
+		if status, ok := getResourceStatus(resource, filepath.Join(geolDir, resource+".json")); ok {
+			statuses = append(statuses, status)
+		}
+	}
+
+	var full []ResourceStatus
+	for kind, endpoint := range map[string]string{ReleasesCacheDir: "products/", CategoriesCacheDir: "categories/", TagsCacheDir: "tags/"} {
+		dir := filepath.Join(geolDir, kind)
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			name, found := strings.CutSuffix(e.Name(), ".json")
+			if !found {
+				continue
+			}
+			if status, ok := getResourceStatus(endpoint+name, filepath.Join(dir, e.Name())); ok {
+				full = append(full, status)
+			}
+		}
+	}
+	sort.Slice(full, func(i, j int) bool { return full[i].Resource < full[j].Resource })
+	return append(statuses, full...), nil
+}
+
+// getResourceStatus builds the status of a resource from its validators, falling back to the
+// modification time of its cached file. It returns false when the file does not exist.
+func getResourceStatus(resource, path string) (ResourceStatus, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ResourceStatus{}, false
+	}
+	status := ResourceStatus{Resource: resource, Path: path, CheckedAt: info.ModTime()}
+	if entry, ok := getCacheEntry(resource); ok {
+		status.CheckedAt = entry.CheckedAt
+		status.ChangedAt = entry.ChangedAt
+		status.Conditional = entry.ETag != "" || entry.LastModified != ""
+	}
+	status.Fresh = time.Since(status.CheckedAt) <= CacheMaxAge
+	return status, true
+}
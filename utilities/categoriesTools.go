@@ -33,17 +33,16 @@ func FetchAndSaveCategories(cmd *cobra.Command) error {
 		log.Error().Err(err).Msg("Error retrieving categories path")
 		return err
 	}
-	// HTTP GET request (extracted)
-	resp, err := GetAPIResponse(APIUrl + "categories")
+	// Conditional HTTP GET request, sending the validators of the previous download
+	body, changed, err := fetchIfModified("categories", categoriesPath)
 	if err != nil {
 		log.Error().Err(err).Msg("Error during HTTP request")
 		return err
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Error().Err(err).Msg("Error closing response body")
-		}
-	}()
+	if !changed {
+		log.Info().Str("resource", "categories").Int64("elapsed time (ms)", time.Since(start).Milliseconds()).Msg("Not modified since the last refresh, keeping the cached file")
+		return saveCacheValidators()
+	}
 
 	// define structure to parse the response
 	type apiResponse struct {
@@ -51,8 +50,7 @@ func FetchAndSaveCategories(cmd *cobra.Command) error {
 	}
 	var apiResp apiResponse
 
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&apiResp); err != nil {
+	if err := json.Unmarshal(body, &apiResp); err != nil {
 		log.Error().Err(err).Msg("Error decoding JSON response")
 		return err
 	}
@@ -81,6 +79,11 @@ func FetchAndSaveCategories(cmd *cobra.Command) error {
 		return err
 	}
 
+	if err := saveCacheValidators(); err != nil {
+		log.Error().Err(err).Msg("Error writing cache validators")
+		return err
+	}
+
 	elapsed := time.Since(start).Milliseconds()
 	log.Info().Int("Number of categories", len(categories)).Int64("elapsed time (ms)", elapsed).Msg("")
 	return nil
@@ -91,6 +94,9 @@ func GetCategoriesWithCacheRefresh(cmd *cobra.Command, categoriesPath string) (C
 	categories := make(CategoriesFile)
 	if err := readAndUnmarshalCategories(categoriesPath, &categories); err != nil {
 		log.Error().Err(err).Msg("Error parsing JSON")
+		if Offline {
+			return categories, err
+		}
 		log.Warn().Msg("Trying to refresh the cache now...")
 		if err := FetchAndSaveCategories(cmd); err != nil {
 			log.Error().Err(err).Msg("Error refreshing cache")
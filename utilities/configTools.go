@@ -0,0 +1,110 @@
+package utilities
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the user settings read from the geol configuration file (see GetConfigPath).
+// Every field is optional, an empty value keeps the built-in default.
+type Config struct {
+	// APIUrl is the base URL of the endoflife.date API, or of a mirror ('geol mirror serve').
+	APIUrl string `yaml:"api_url,omitempty"`
+	// DescribeUrl is the base URL of the product markdown files used by 'geol product describe'.
+	DescribeUrl string `yaml:"describe_url,omitempty"`
+	// ReleaseCheckUrl is the GitHub API endpoint used to check for a newer geol release.
+	ReleaseCheckUrl string `yaml:"release_check_url,omitempty"`
+	// CacheMaxAge is the age after which the local cache is refreshed, 24h by default.
+	CacheMaxAge time.Duration `yaml:"cache_max_age,omitempty"`
+	// HTTP configures timeouts, retries, rate limiting, proxy and CA bundle of the HTTP client.
+	HTTP HTTPSettings `yaml:"http,omitempty"`
+	// Scoring overrides the debt scoring model of 'geol check' (see DefaultScoringModel).
+	Scoring ScoringModel `yaml:"scoring,omitempty"`
+	// Notify are the webhooks notified after every 'geol check' (see NotifyTarget).
+	Notify []NotifyTarget `yaml:"notify,omitempty"`
+}
+
+// GetGeolDir returns the geol directory in the user's config directory, where the cache lives.
+func GetGeolDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "geol"), nil
+}
+
+// GetHistoryPath returns the path to the SQLite store of the runs recorded by 'geol check --record'.
+func GetHistoryPath() (string, error) {
+	geolDir, err := GetGeolDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(geolDir, "history.sqlite"), nil
+}
+
+// GetConfigPath returns the path to the geol configuration file: $GEOL_CONFIG when set,
+// config.yaml in the geol config directory otherwise.
+func GetConfigPath() (string, error) {
+	if path := os.Getenv("GEOL_CONFIG"); path != "" {
+		return path, nil
+	}
+	geolDir, err := GetGeolDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(geolDir, "config.yaml"), nil
+}
+
+// LoadConfig reads the geol configuration file on top of the default settings. A missing file
+// is not an error and returns the defaults.
+func LoadConfig() (Config, error) {
+	config := Config{HTTP: DefaultHTTPSettings, Scoring: DefaultScoringModel}
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return config, err
+	}
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("error parsing %s: %w", configPath, err)
+	}
+	return config, nil
+}
+
+// InitDataSource sets the URLs every fetcher uses. The API URL comes from the --api-url flag,
+// then the GEOL_API_URL environment variable, then the configuration file.
+func InitDataSource(config Config, apiURLFlag string) {
+	apiURL := apiURLFlag
+	if apiURL == "" {
+		apiURL = os.Getenv("GEOL_API_URL")
+	}
+	if apiURL == "" {
+		apiURL = config.APIUrl
+	}
+	if apiURL != "" {
+		APIUrl = withTrailingSlash(apiURL)
+	}
+	if config.DescribeUrl != "" {
+		DescribeUrl = withTrailingSlash(config.DescribeUrl)
+	}
+	if config.ReleaseCheckUrl != "" {
+		ReleaseCheckUrl = config.ReleaseCheckUrl
+	}
+}
+
+func withTrailingSlash(url string) string {
+	if strings.HasSuffix(url, "/") {
+		return url
+	}
+	return url + "/"
+}
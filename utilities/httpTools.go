@@ -0,0 +1,243 @@
+package utilities
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/phuslu/log"
+)
+
+// HTTPSettings configures the HTTP client shared by every geol request, under the http key
+// of the configuration file.
+type HTTPSettings struct {
+	// ConnectTimeout bounds the TCP connection and TLS handshake.
+	ConnectTimeout time.Duration `yaml:"connect_timeout,omitempty"`
+	// ReadTimeout bounds the time to receive the whole response once connected.
+	ReadTimeout time.Duration `yaml:"read_timeout,omitempty"`
+	// Retries is the number of retries on network errors, 429 and 5xx responses.
+	Retries int `yaml:"retries"`
+	// Backoff is the delay before the first retry, doubled on every following retry.
+	Backoff time.Duration `yaml:"backoff,omitempty"`
+	// RateLimit is the maximum number of requests per second, 0 for no limit.
+	RateLimit float64 `yaml:"rate_limit"`
+	// Burst is the number of requests allowed at once before RateLimit applies.
+	Burst int `yaml:"burst,omitempty"`
+	// Proxy is the proxy URL, HTTP_PROXY/HTTPS_PROXY/NO_PROXY are used when empty.
+	Proxy string `yaml:"proxy,omitempty"`
+	// CABundle is a PEM file of additional certificate authorities to trust.
+	CABundle string `yaml:"ca_bundle,omitempty"`
+}
+
+// DefaultHTTPSettings are the HTTP settings used when the configuration file does not override them.
+var DefaultHTTPSettings = HTTPSettings{
+	ConnectTimeout: 10 * time.Second,
+	ReadTimeout:    30 * time.Second,
+	Retries:        3,
+	Backoff:        500 * time.Millisecond,
+	RateLimit:      10,
+	Burst:          10,
+}
+
+// maxRetryDelay caps both the exponential backoff and the server-provided Retry-After delay.
+const maxRetryDelay = 60 * time.Second
+
+// httpClient is the client shared by every request geol makes (see InitHTTPClient).
+var httpClient = newRetryClient(http.DefaultClient, DefaultHTTPSettings)
+
+type retryClient struct {
+	client  *http.Client
+	retries int
+	backoff time.Duration
+	limiter *tokenBucket
+}
+
+func newRetryClient(client *http.Client, settings HTTPSettings) *retryClient {
+	c := &retryClient{client: client, retries: settings.Retries, backoff: settings.Backoff}
+	if settings.RateLimit > 0 {
+		c.limiter = newTokenBucket(settings.RateLimit, settings.Burst)
+	}
+	return c
+}
+
+// InitHTTPClient builds the shared HTTP client from the given settings. The legacy
+// GEOL_API_DELAY_MS environment variable, when set, turns into the equivalent rate limit.
+func InitHTTPClient(settings HTTPSettings) error {
+	if settings.ConnectTimeout <= 0 {
+		settings.ConnectTimeout = DefaultHTTPSettings.ConnectTimeout
+	}
+	if settings.ReadTimeout <= 0 {
+		settings.ReadTimeout = DefaultHTTPSettings.ReadTimeout
+	}
+	if settings.Backoff <= 0 {
+		settings.Backoff = DefaultHTTPSettings.Backoff
+	}
+	if settings.Retries < 0 {
+		settings.Retries = 0
+	}
+	if delayStr := os.Getenv("GEOL_API_DELAY_MS"); delayStr != "" {
+		if delayMs, err := strconv.Atoi(delayStr); err == nil && delayMs > 0 {
+			settings.RateLimit = 1000 / float64(delayMs)
+			settings.Burst = 1
+			log.Info().Msgf("GEOL_API_DELAY_MS: rate limit set to one request every %d ms", delayMs)
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: settings.ConnectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = settings.ConnectTimeout
+	transport.ResponseHeaderTimeout = settings.ReadTimeout
+
+	if settings.Proxy != "" {
+		proxyURL, err := url.Parse(settings.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid http.proxy %q: %w", settings.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if settings.CABundle != "" {
+		pem, err := os.ReadFile(settings.CABundle)
+		if err != nil {
+			return fmt.Errorf("error reading http.ca_bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate found in http.ca_bundle %s", settings.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	httpClient = newRetryClient(&http.Client{
+		Transport: transport,
+		Timeout:   settings.ConnectTimeout + settings.ReadTimeout,
+	}, settings)
+	return nil
+}
+
+// HTTPGet performs a GET request through the shared HTTP client (see HTTPDo).
+func HTTPGet(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return HTTPDo(req)
+}
+
+// HTTPDo sends a request through the shared HTTP client. It waits for the rate limiter, then
+// retries with exponential backoff on network errors, 429 and 5xx responses, honouring the
+// Retry-After header. The last response is returned as is once retries are exhausted.
+func HTTPDo(req *http.Request) (*http.Response, error) {
+	return httpClient.do(req, func(resp *http.Response, err error) bool {
+		return err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	})
+}
+
+// HTTPDoWithoutReplay sends a request that must not be delivered twice, e.g. a webhook POST,
+// through the shared HTTP client. Unlike HTTPDo, it only retries when the server cannot have
+// processed the request: on connection failures and 429 responses, never on 5xx responses.
+func HTTPDoWithoutReplay(req *http.Request) (*http.Response, error) {
+	return httpClient.do(req, func(resp *http.Response, err error) bool {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) {
+			return opErr.Op == "dial"
+		}
+		return err == nil && resp.StatusCode == http.StatusTooManyRequests
+	})
+}
+
+// do sends the request, retrying while retryable returns true for its outcome.
+func (c *retryClient) do(req *http.Request, retryable func(*http.Response, error) bool) (*http.Response, error) {
+	delay := c.backoff
+	for attempt := 0; ; attempt++ {
+		if c.limiter != nil {
+			c.limiter.wait()
+		}
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.client.Do(req)
+		if !retryable(resp, err) || attempt >= c.retries || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		wait := delay
+		if err != nil {
+			log.Debug().Err(err).Msgf("Request to %s failed, retrying in %v (%d/%d)", req.URL, wait, attempt+1, c.retries)
+		} else {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = retryAfter
+			}
+			log.Debug().Msgf("Request to %s returned %s, retrying in %v (%d/%d)", req.URL, resp.Status, wait, attempt+1, c.retries)
+			if cerr := resp.Body.Close(); cerr != nil {
+				log.Debug().Err(cerr).Msg("Error closing response body")
+			}
+		}
+		time.Sleep(min(wait, maxRetryDelay))
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// parseRetryAfter reads a Retry-After header, given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// tokenBucket is a minimal token-bucket rate limiter: up to burst requests at once, then rate
+// requests per second. It is safe for concurrent use.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait blocks until a token is available. Tokens are reserved before sleeping, so concurrent
+// callers queue up instead of all waking at once.
+func (b *tokenBucket) wait() {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+	time.Sleep(delay)
+}
@@ -0,0 +1,112 @@
+package utilities
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultAlarmDays are the days before an event at which calendar reminders fire.
+var DefaultAlarmDays = []int{180, 90, 30}
+
+// ValidateAlarmDays returns an error when a reminder is not at least one day before its event.
+func ValidateAlarmDays(alarmDays []int) error {
+	for _, days := range alarmDays {
+		if days < 1 {
+			return fmt.Errorf("--alarms must be at least 1 day before each date, got %d", days)
+		}
+	}
+	return nil
+}
+
+// CalendarEvent is an all-day event of an iCalendar file.
+type CalendarEvent struct {
+	// UID identifies the event across exports, so that subscribed calendars update it (see CalendarUID)
+	UID         string
+	Date        string // YYYY-MM-DD
+	Summary     string
+	Description string
+}
+
+var uidUnsafe = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// CalendarUID returns a stable event UID made of parts, e.g. the product, cycle and kind of date.
+func CalendarUID(parts ...string) string {
+	for i, part := range parts {
+		parts[i] = strings.Trim(uidUnsafe.ReplaceAllString(strings.ToLower(part), "-"), "-")
+	}
+	return strings.Join(parts, "-") + "@geol.opt-nc.github.io"
+}
+
+// WriteICS writes the events as an iCalendar (RFC 5545) file, with a display alarm the given
+// number of days before each event. Events with an invalid date are skipped.
+func WriteICS(w io.Writer, name string, events []CalendarEvent, alarmDays []int) error {
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//opt-nc//geol " + Version + "//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + icsText(name),
+	}
+	for _, e := range events {
+		date, err := time.Parse("2006-01-02", e.Date)
+		if err != nil {
+			continue
+		}
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+e.UID,
+			"DTSTAMP:"+stamp,
+			"DTSTART;VALUE=DATE:"+date.Format("20060102"),
+			"DTEND;VALUE=DATE:"+date.AddDate(0, 0, 1).Format("20060102"),
+			"SUMMARY:"+icsText(e.Summary),
+			"DESCRIPTION:"+icsText(e.Description),
+			"TRANSP:TRANSPARENT",
+		)
+		for _, days := range alarmDays {
+			lines = append(lines,
+				"BEGIN:VALARM",
+				"ACTION:DISPLAY",
+				fmt.Sprintf("TRIGGER:-P%dD", days),
+				"DESCRIPTION:"+icsText(fmt.Sprintf("%s in %d days", e.Summary, days)),
+				"END:VALARM",
+			)
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(icsFold(line))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// icsText escapes a TEXT value.
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// icsFold folds a content line into lines of at most 75 octets, ended by CRLF, without splitting
+// UTF-8 characters.
+func icsFold(line string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
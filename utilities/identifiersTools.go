@@ -0,0 +1,108 @@
+package utilities
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+)
+
+// ProductIdentifier is an identifier of a product on endoflife.date (e.g. a purl, a CPE or a
+// repology project).
+type ProductIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// IdentifiersFile maps every product name to its identifiers.
+type IdentifiersFile map[string][]ProductIdentifier
+
+// GetIdentifiersPath returns the path to the identifiers.json file in the user's config directory.
+func GetIdentifiersPath() (string, error) {
+	geolDir, err := GetGeolDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(geolDir, "identifiers.json"), nil
+}
+
+// GetIdentifiersWithCacheRefresh returns the identifiers of every product from identifiers.json,
+// rebuilding it from the product payloads when it is missing or older than CacheMaxAge. In
+// offline mode the payloads are read from the full cache, and a stale file is used as is.
+func GetIdentifiersWithCacheRefresh(cmd *cobra.Command) (IdentifiersFile, error) {
+	identifiersPath, err := GetIdentifiersPath()
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(identifiersPath); err == nil && (Offline || time.Since(info.ModTime()) <= CacheMaxAge) {
+		data, err := os.ReadFile(identifiersPath)
+		if err == nil {
+			identifiers := IdentifiersFile{}
+			if err := json.Unmarshal(data, &identifiers); err == nil {
+				return identifiers, nil
+			}
+		}
+		log.Warn().Msg("Invalid identifiers cache, rebuilding it...")
+	}
+	return FetchAndSaveIdentifiers(cmd)
+}
+
+// FetchAndSaveIdentifiers collects the identifiers of every product from their payloads, fetched
+// in parallel, and saves them to identifiers.json. Products that cannot be fetched are skipped.
+func FetchAndSaveIdentifiers(cmd *cobra.Command) (IdentifiersFile, error) {
+	start := time.Now()
+	productsPath, err := GetProductsPath()
+	if err != nil {
+		return nil, err
+	}
+	products, err := GetProductsWithCacheRefresh(cmd, productsPath)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(products.Products))
+	for name := range products.Products {
+		names = append(names, name)
+	}
+	log.Info().Msgf("Collecting the identifiers of %d products...", len(names))
+
+	identifiers := IdentifiersFile{}
+	var mu sync.Mutex
+	RunConcurrently(len(names), func(i int) {
+		body, err := FetchProductBody(names[i])
+		if err != nil {
+			log.Warn().Err(err).Msgf("Error requesting %s, skipping", names[i])
+			return
+		}
+		var payload struct {
+			Result struct {
+				Identifiers []ProductIdentifier `json:"identifiers"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			log.Warn().Err(err).Msgf("Error decoding JSON for %s, skipping", names[i])
+			return
+		}
+		mu.Lock()
+		identifiers[names[i]] = payload.Result.Identifiers
+		mu.Unlock()
+	})
+
+	data, err := json.MarshalIndent(identifiers, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	identifiersPath, err := GetIdentifiersPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(identifiersPath, data, 0o644); err != nil {
+		log.Error().Err(err).Msg("Error writing identifiers file")
+		return nil, err
+	}
+	log.Info().Int("Number of products with identifiers", len(identifiers)).Int64("elapsed time (ms)", time.Since(start).Milliseconds()).Msg("")
+	return identifiers, nil
+}
@@ -0,0 +1,74 @@
+package utilities
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"text/template"
+)
+
+// NotifyFormats are the payload formats of a webhook.
+var NotifyFormats = []string{"json", "slack", "teams", "template"}
+
+// NotifyConditions are the conditions on which a webhook is notified.
+var NotifyConditions = []string{"always", "violations", "new-violations", "score-drop"}
+
+// NotifyTarget is a webhook notified with a summary after 'geol check', set by the notify:
+// section of the configuration file and of stack files. The URL and header values may
+// reference environment variables ($VAR or ${VAR}), so that secrets stay out of the files.
+type NotifyTarget struct {
+	URL string `yaml:"url"`
+	// Format is json (default), slack, teams or template
+	Format string `yaml:"format,omitempty"`
+	// Template is the Go template of the body, for the template format
+	Template string            `yaml:"template,omitempty"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+	// On is always (default), violations, new-violations or score-drop
+	On string `yaml:"on,omitempty"`
+	// MinScoreDrop is the score drop that triggers a score-drop notification, 1 by default
+	MinScoreDrop int `yaml:"min_score_drop,omitempty"`
+}
+
+// Notify are the webhooks of the configuration file, notified after every 'geol check'.
+var Notify []NotifyTarget
+
+// InitNotify sets Notify from the notify: section of the configuration file.
+func InitNotify(config Config) error {
+	for i, target := range config.Notify {
+		if err := target.Validate(); err != nil {
+			return fmt.Errorf("notify[%d]: %w", i, err)
+		}
+	}
+	Notify = config.Notify
+	return nil
+}
+
+// Validate checks the format, condition and template of the target.
+func (t NotifyTarget) Validate() error {
+	if t.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if t.Format != "" && !slices.Contains(NotifyFormats, t.Format) {
+		return fmt.Errorf("format must be one of %v, got '%s'", NotifyFormats, t.Format)
+	}
+	if (t.Format == "template") != (t.Template != "") {
+		return fmt.Errorf("template must be set with format: template, and only then")
+	}
+	if t.Template != "" {
+		if _, err := template.New("notify").Parse(t.Template); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	}
+	if t.On != "" && !slices.Contains(NotifyConditions, t.On) {
+		return fmt.Errorf("on must be one of %v, got '%s'", NotifyConditions, t.On)
+	}
+	if t.MinScoreDrop < 0 || (t.MinScoreDrop != 0 && t.On != "score-drop") {
+		return fmt.Errorf("min_score_drop must be positive, and only set with on: score-drop")
+	}
+	return nil
+}
+
+// ExpandedURL returns the URL with its environment variables expanded.
+func (t NotifyTarget) ExpandedURL() string {
+	return os.ExpandEnv(t.URL)
+}
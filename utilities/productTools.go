@@ -43,7 +43,7 @@ func AnalyzeCacheProductsValidity(cmd *cobra.Command) {
 	}
 
 	modTime := info.ModTime()
-	CheckCacheTimeAndUpdateGeneric(modTime, 24*time.Hour, cmd)
+	CheckCacheTimeAndUpdateGeneric(modTime, CacheMaxAge, cmd)
 }
 
 func FetchAndSaveProducts(cmd *cobra.Command) error {
@@ -60,17 +60,16 @@ func FetchAndSaveProducts(cmd *cobra.Command) error {
 		return err
 	}
 
-	// HTTP GET request (extracted)
-	resp, err := GetAPIResponse(APIUrl + "products")
+	// Conditional HTTP GET request, sending the validators of the previous download
+	body, changed, err := fetchIfModified("products", productsPath)
 	if err != nil {
 		log.Error().Err(err).Msg("Error during HTTP request")
 		return err
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Error().Err(err).Msg("Error closing response body")
-		}
-	}()
+	if !changed {
+		log.Info().Str("resource", "products").Int64("elapsed time (ms)", time.Since(start).Milliseconds()).Msg("Not modified since the last refresh, keeping the cached file")
+		return saveCacheValidators()
+	}
 
 	// Define structure to parse the response
 	type apiResponse struct {
@@ -78,8 +77,7 @@ func FetchAndSaveProducts(cmd *cobra.Command) error {
 	}
 	var apiResp apiResponse
 
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&apiResp); err != nil {
+	if err := json.Unmarshal(body, &apiResp); err != nil {
 		log.Error().Err(err).Msg("Error decoding JSON")
 		return err
 	}
@@ -108,6 +106,12 @@ func FetchAndSaveProducts(cmd *cobra.Command) error {
 		log.Error().Err(err).Msg("Error writing file")
 		return err
 	}
+	// Save the validators of the download, for the next conditional request
+	if err := saveCacheValidators(); err != nil {
+		log.Error().Err(err).Msg("Error writing cache validators")
+		return err
+	}
+
 	// Print the number of products written and elapsed time
 	elapsed := time.Since(start).Milliseconds()
 	log.Info().Int("Number of products", len(products.Products)).Int64("elapsed time (ms)", elapsed).Msg("")
@@ -119,6 +123,9 @@ func GetProductsWithCacheRefresh(cmd *cobra.Command, productsPath string) (Produ
 	var products ProductsFile
 	if err := readAndUnmarshalProducts(productsPath, &products); err != nil {
 		log.Error().Err(err).Msg("Error parsing JSON")
+		if Offline {
+			return products, err
+		}
 		log.Warn().Msg("Trying to refresh the cache now...")
 		if err := FetchAndSaveProducts(cmd); err != nil {
 			log.Error().Err(err).Msg("Error refreshing cache")
@@ -0,0 +1,231 @@
+package utilities
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/phuslu/log"
+	"github.com/spf13/cobra"
+)
+
+// Offline is set from the global --offline flag. When true, every API payload is read from the
+// full local cache (see FetchAndSaveFullCache) and no network request is made.
+var Offline bool
+
+// Sub-directories of the geol config directory holding the full cache, one JSON payload per entry.
+// 'geol mirror serve' serves them back in the endoflife.date API shape.
+const (
+	ReleasesCacheDir   = "releases"
+	CategoriesCacheDir = "categories"
+	TagsCacheDir       = "tags"
+)
+
+// getFullCacheDir returns the path of a full cache sub-directory (e.g. geol/releases).
+func getFullCacheDir(kind string) (string, error) {
+	geolDir, err := GetGeolDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(geolDir, kind), nil
+}
+
+// FetchProductBody returns the raw products/{name} payload, from the API or, in offline mode,
+// from the full local cache.
+func FetchProductBody(name string) ([]byte, error) {
+	return fetchAPIResource(ReleasesCacheDir, "products/", name)
+}
+
+// FetchCategoryBody returns the raw categories/{name} payload, from the API or, in offline mode,
+// from the full local cache.
+func FetchCategoryBody(name string) ([]byte, error) {
+	return fetchAPIResource(CategoriesCacheDir, "categories/", name)
+}
+
+// FetchTagBody returns the raw tags/{name} payload, from the API or, in offline mode,
+// from the full local cache.
+func FetchTagBody(name string) ([]byte, error) {
+	return fetchAPIResource(TagsCacheDir, "tags/", name)
+}
+
+// fetchAPIResource reads APIUrl+endpoint+name, or its cached copy under kind when offline.
+func fetchAPIResource(kind, endpoint, name string) ([]byte, error) {
+	if Offline {
+		dir, err := getFullCacheDir(kind)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s%s is not available in the offline cache, run 'geol cache refresh --full' while online", endpoint, name)
+		}
+		return data, err
+	}
+
+	resp, err := GetAPIResponse(APIUrl + endpoint + name)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if cerr := resp.Body.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading response for %s%s: %w", endpoint, name, err)
+	}
+	return body, nil
+}
+
+// FetchAndSaveFullCache downloads the release payload of every cached product, and the product
+// list of every cached category and tag, so that all commands can later run with --offline.
+func FetchAndSaveFullCache(cmd *cobra.Command) error {
+	if Offline {
+		return fmt.Errorf("cannot refresh the cache in offline mode")
+	}
+	start := time.Now()
+
+	productsPath, err := GetProductsPath()
+	if err != nil {
+		log.Error().Err(err).Msg("Error retrieving products path")
+		return err
+	}
+	products, err := GetProductsWithCacheRefresh(cmd, productsPath)
+	if err != nil {
+		return err
+	}
+	productNames := make([]string, 0, len(products.Products))
+	for name := range products.Products {
+		productNames = append(productNames, name)
+	}
+
+	categoriesPath, err := GetCategoriesPath()
+	if err != nil {
+		log.Error().Err(err).Msg("Error retrieving categories path")
+		return err
+	}
+	categories, err := GetCategoriesWithCacheRefresh(cmd, categoriesPath)
+	if err != nil {
+		return err
+	}
+	categoryNames := make([]string, 0, len(categories))
+	for name := range categories {
+		categoryNames = append(categoryNames, name)
+	}
+
+	tagsPath, err := GetTagsPath()
+	if err != nil {
+		log.Error().Err(err).Msg("Error retrieving tags path")
+		return err
+	}
+	tags, err := GetTagsWithCacheRefresh(cmd, tagsPath)
+	if err != nil {
+		return err
+	}
+	tagNames := make([]string, 0, len(tags))
+	for name := range tags {
+		tagNames = append(tagNames, name)
+	}
+
+	if err := saveAPIResources(ReleasesCacheDir, "products/", productNames); err != nil {
+		return err
+	}
+	if err := saveAPIResources(CategoriesCacheDir, "categories/", categoryNames); err != nil {
+		return err
+	}
+	if err := saveAPIResources(TagsCacheDir, "tags/", tagNames); err != nil {
+		return err
+	}
+
+	elapsed := time.Since(start).Milliseconds()
+	log.Info().
+		Int("Number of product releases", len(productNames)).
+		Int("Number of category listings", len(categoryNames)).
+		Int("Number of tag listings", len(tagNames)).
+		Int64("elapsed time (ms)", elapsed).Msg("")
+	return nil
+}
+
+// saveAPIResources downloads APIUrl+endpoint+name for every name into the kind cache directory,
+// keeping the cached copy of the payloads the server reports as not modified.
+// Files are written to a temporary directory first so a failed refresh keeps the previous cache.
+func saveAPIResources(kind, endpoint string, names []string) error {
+	dir, err := getFullCacheDir(kind)
+	if err != nil {
+		return err
+	}
+	tmpDir := dir + ".tmp"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		log.Error().Err(err).Msg("Error removing temporary cache directory")
+		return err
+	}
+	if err := createDirectoryIfNotExists(tmpDir); err != nil {
+		log.Error().Err(err).Msg("Error ensuring directory exists")
+		return err
+	}
+
+	sort.Strings(names)
+	changedCount := 0
+	for i, name := range names {
+		log.Debug().Msgf("Caching %s%s [%d/%d]", endpoint, name, i+1, len(names))
+		cachedPath := filepath.Join(dir, name+".json")
+		body, changed, err := fetchIfModified(endpoint+name, cachedPath)
+		if err != nil {
+			log.Error().Err(err).Msgf("Error fetching %s%s", endpoint, name)
+			return err
+		}
+		if changed {
+			changedCount++
+		} else if body, err = os.ReadFile(cachedPath); err != nil {
+			log.Error().Err(err).Msg("Error reading cached file")
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, name+".json"), body, 0o644); err != nil {
+			log.Error().Err(err).Msg("Error writing file")
+			return err
+		}
+	}
+	log.Debug().Msgf("%d/%d %s payloads changed since the last refresh", changedCount, len(names), kind)
+
+	if err := saveCacheValidators(); err != nil {
+		log.Error().Err(err).Msg("Error writing cache validators")
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		log.Error().Err(err).Msg("Error removing old cache directory")
+		return err
+	}
+	return os.Rename(tmpDir, dir)
+}
+
+// CountCachedReleases returns the number of product release payloads in the full cache.
+func CountCachedReleases() (int, error) {
+	dir, err := getFullCacheDir(ReleasesCacheDir)
+	if err != nil {
+		return 0, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// RemoveFullCache deletes the release, category and tag payloads of the full cache.
+func RemoveFullCache() error {
+	for _, kind := range []string{ReleasesCacheDir, CategoriesCacheDir, TagsCacheDir} {
+		dir, err := getFullCacheDir(kind)
+		if err != nil {
+			return err
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
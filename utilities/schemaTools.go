@@ -0,0 +1,46 @@
+package utilities
+
+import (
+	"errors"
+	"fmt"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/encoding/yaml"
+)
+
+// StackSchema is the content of geol_stack.cue, initialized from main package at runtime.
+var StackSchema string
+
+// ValidateStack validates a stack YAML document against the geol_stack.cue schema. The name is
+// the file name used in error messages.
+func ValidateStack(name string, data []byte) error {
+	if StackSchema == "" {
+		return errors.New("the geol_stack.cue schema is not available")
+	}
+	ctx := cuecontext.New()
+
+	cueSchema := ctx.CompileString(StackSchema)
+	if cueSchema.Err() != nil {
+		return fmt.Errorf("CUE schema compilation error: %w", cueSchema.Err())
+	}
+
+	// Convert YAML to CUE value
+	yamlExpr, err := yaml.Extract(name, data)
+	if err != nil {
+		return fmt.Errorf("YAML extraction error: %w", err)
+	}
+
+	yamlValue := ctx.BuildFile(yamlExpr)
+	if yamlValue.Err() != nil {
+		return fmt.Errorf("value construction error: %w", yamlValue.Err())
+	}
+
+	// Unify and validate
+	unified := cueSchema.Unify(yamlValue)
+	if err := unified.Validate(cue.Concrete(true)); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package utilities
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ScoringTiers are the debt scores given to a stack item, from 0 (past EOL) to 100 (up to date).
+type ScoringTiers struct {
+	PastEol             int `yaml:"past_eol" json:"past_eol"`
+	NearingEol          int `yaml:"nearing_eol" json:"nearing_eol"`
+	NearingEolLts       int `yaml:"nearing_eol_lts" json:"nearing_eol_lts"`
+	NearingEolLatestLts int `yaml:"nearing_eol_latest_lts" json:"nearing_eol_latest_lts"`
+	MajorLag            int `yaml:"major_lag" json:"major_lag"`
+	MajorLagLts         int `yaml:"major_lag_lts" json:"major_lag_lts"`
+	MajorLagLatestLts   int `yaml:"major_lag_latest_lts" json:"major_lag_latest_lts"`
+	MinorLag            int `yaml:"minor_lag" json:"minor_lag"`
+	Latest              int `yaml:"latest" json:"latest"`
+}
+
+// ScoringBands are the lowest stack scores of the healthy (green) and needs attention (orange)
+// bands, lower scores being critical (red).
+type ScoringBands struct {
+	Healthy        int `yaml:"healthy" json:"healthy"`
+	NeedsAttention int `yaml:"needs_attention" json:"needs_attention"`
+}
+
+// ScoringModel is the debt scoring model of 'geol check', set by the scoring: block of the
+// configuration file and of stack files.
+type ScoringModel struct {
+	// RiskThresholdDays is the number of days before EOL at which a component is nearing EOL
+	RiskThresholdDays int          `yaml:"risk_threshold_days" json:"risk_threshold_days"`
+	Tiers             ScoringTiers `yaml:"tiers" json:"tiers"`
+	Bands             ScoringBands `yaml:"bands" json:"bands"`
+}
+
+// DefaultScoringModel mirrors the compute_health_score() logic of the geol-check-report.qmd notebook.
+var DefaultScoringModel = ScoringModel{
+	RiskThresholdDays: 180,
+	Tiers: ScoringTiers{
+		PastEol:             0,
+		NearingEol:          30,
+		NearingEolLts:       35,
+		NearingEolLatestLts: 45,
+		MajorLag:            60,
+		MajorLagLts:         75,
+		MajorLagLatestLts:   95,
+		MinorLag:            80,
+		Latest:              100,
+	},
+	Bands: ScoringBands{Healthy: 80, NeedsAttention: 50},
+}
+
+// Scoring is the scoring model of the configuration file, which stack files may override.
+var Scoring = DefaultScoringModel
+
+// InitScoring sets Scoring from the scoring: block of the configuration file.
+func InitScoring(config Config) error {
+	if err := config.Scoring.Validate(); err != nil {
+		return err
+	}
+	Scoring = config.Scoring
+	return nil
+}
+
+// Validate checks that the thresholds are positive and that the scores are between 0 and 100.
+func (m ScoringModel) Validate() error {
+	if m.RiskThresholdDays <= 0 {
+		return fmt.Errorf("scoring.risk_threshold_days must be positive, got %d", m.RiskThresholdDays)
+	}
+	scores := []struct {
+		name  string
+		value int
+	}{
+		{"tiers.past_eol", m.Tiers.PastEol},
+		{"tiers.nearing_eol", m.Tiers.NearingEol},
+		{"tiers.nearing_eol_lts", m.Tiers.NearingEolLts},
+		{"tiers.nearing_eol_latest_lts", m.Tiers.NearingEolLatestLts},
+		{"tiers.major_lag", m.Tiers.MajorLag},
+		{"tiers.major_lag_lts", m.Tiers.MajorLagLts},
+		{"tiers.major_lag_latest_lts", m.Tiers.MajorLagLatestLts},
+		{"tiers.minor_lag", m.Tiers.MinorLag},
+		{"tiers.latest", m.Tiers.Latest},
+		{"bands.healthy", m.Bands.Healthy},
+		{"bands.needs_attention", m.Bands.NeedsAttention},
+	}
+	for _, score := range scores {
+		if score.value < 0 || score.value > 100 {
+			return fmt.Errorf("scoring.%s must be between 0 and 100, got %d", score.name, score.value)
+		}
+	}
+	if m.Bands.NeedsAttention > m.Bands.Healthy {
+		return errors.New("scoring.bands.needs_attention must not be greater than scoring.bands.healthy")
+	}
+	return nil
+}
@@ -10,8 +10,6 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const tagBaseURL = "https://endoflife.date/api/v1/tags/"
-
 type Tag struct {
 	Name string `json:"name"`
 	Uri  string `json:"uri"`
@@ -35,17 +33,16 @@ func FetchAndSaveTags(cmd *cobra.Command) error {
 		log.Error().Err(err).Msg("Error retrieving tags path")
 		return err
 	}
-	// HTTP GET request (extracted)
-	resp, err := GetAPIResponse(APIUrl + "tags")
+	// Conditional HTTP GET request, sending the validators of the previous download
+	body, changed, err := fetchIfModified("tags", tagsPath)
 	if err != nil {
 		log.Error().Err(err).Msg("Error during HTTP request")
 		return err
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Error().Err(err).Msg("Error closing response body")
-		}
-	}()
+	if !changed {
+		log.Info().Str("resource", "tags").Int64("elapsed time (ms)", time.Since(start).Milliseconds()).Msg("Not modified since the last refresh, keeping the cached file")
+		return saveCacheValidators()
+	}
 
 	// define structure to parse the response
 	type apiResponse struct {
@@ -53,8 +50,7 @@ func FetchAndSaveTags(cmd *cobra.Command) error {
 	}
 	var apiResp apiResponse
 
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&apiResp); err != nil {
+	if err := json.Unmarshal(body, &apiResp); err != nil {
 		log.Error().Err(err).Msg("Error decoding JSON response")
 		return err
 	}
@@ -83,6 +79,11 @@ func FetchAndSaveTags(cmd *cobra.Command) error {
 		return err
 	}
 
+	if err := saveCacheValidators(); err != nil {
+		log.Error().Err(err).Msg("Error writing cache validators")
+		return err
+	}
+
 	elapsed := time.Since(start).Milliseconds()
 	log.Info().Int("Number of tags", len(tags)).Int64("elapsed time (ms)", elapsed).Msg("")
 	return nil
@@ -93,6 +94,9 @@ func GetTagsWithCacheRefresh(cmd *cobra.Command, tagsPath string) (TagsFile, err
 	tags := make(TagsFile)
 	if err := readAndUnmarshalTags(tagsPath, &tags); err != nil {
 		log.Error().Err(err).Msg("Error parsing JSON")
+		if Offline {
+			return tags, err
+		}
 		log.Warn().Msg("Trying to refresh the cache now...")
 		if err := FetchAndSaveTags(cmd); err != nil {
 			log.Error().Err(err).Msg("Error refreshing cache")
@@ -126,7 +130,7 @@ func ConvertTagStringsToTags(tagStrings []string) []Tag {
 	for i, tagName := range tagStrings {
 		tags[i] = Tag{
 			Name: tagName,
-			Uri:  tagBaseURL + tagName,
+			Uri:  APIUrl + "tags/" + tagName,
 		}
 	}
 	return tags
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/phuslu/log"
@@ -16,7 +17,11 @@ import (
 // CheckCacheTimeAndUpdateGeneric logs the cache mod time and updates the cache if older than maxAge using RefreshAllCaches.
 func CheckCacheTimeAndUpdateGeneric(modTime time.Time, maxAge time.Duration, cmd *cobra.Command) {
 	if modTime.Before(time.Now().Add(-maxAge)) {
-		log.Warn().Msg("Cache last updated " + modTime.Format("2006-01-02 15:04:05") + ", older than 24 hours. Updating the cache...")
+		if Offline {
+			log.Warn().Msg("Cache last updated " + modTime.Format("2006-01-02 15:04:05") + ", older than " + maxAge.String() + ". Offline mode, using it anyway.")
+			return
+		}
+		log.Warn().Msg("Cache last updated " + modTime.Format("2006-01-02 15:04:05") + ", older than " + maxAge.String() + ". Updating the cache...")
 		RefreshAllCaches(cmd)
 	}
 }
@@ -24,6 +29,10 @@ func CheckCacheTimeAndUpdateGeneric(modTime time.Time, maxAge time.Duration, cmd
 // ensureCacheExistsGeneric checks if the cache file exists, creates it if missing using RefreshAllCaches, and returns its FileInfo or an error.
 func EnsureCacheExistsGeneric(cachePath string, cmd *cobra.Command) (os.FileInfo, error) {
 	info, err := os.Stat(cachePath)
+	if err != nil && Offline {
+		log.Error().Err(err).Str("path", cachePath).Msg("cache not found, run 'geol cache refresh --full' while online before using --offline")
+		return nil, err
+	}
 	if err != nil {
 		log.Warn().Err(err).Str("path", cachePath).Msg("cache not found, creating the cache...")
 		RefreshAllCaches(cmd)
@@ -37,8 +46,15 @@ func EnsureCacheExistsGeneric(cachePath string, cmd *cobra.Command) (os.FileInfo
 	return info, nil
 }
 
+// APIUrl is the base URL of the endoflife.date API (see InitDataSource).
 var APIUrl = "https://endoflife.date/api/v1/"
 
+// DescribeUrl is the base URL of the endoflife.date product markdown files.
+var DescribeUrl = "https://raw.githubusercontent.com/endoflife-date/endoflife.date/refs/heads/master/products/"
+
+// ReleaseCheckUrl is the GitHub API endpoint returning the latest geol release.
+var ReleaseCheckUrl = "https://api.github.com/repos/opt-nc/geol/releases/latest"
+
 func InitLogger(logLevel string) {
 	var level log.Level
 	switch logLevel {
@@ -77,10 +93,24 @@ func RemoveFileIfExists(path string) error {
 	return nil
 }
 
-// GetAPIResponse performs an HTTP GET request to the given URL and returns the response if status is 200.
+// HTTPStatusError is returned by GetAPIResponse when the API answers with a non-200 status.
+type HTTPStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return "unexpected HTTP status: " + e.Status
+}
+
+// GetAPIResponse performs an HTTP GET request to the given URL through the shared HTTP client
+// and returns the response if status is 200.
 // The caller is responsible for closing the response body.
 func GetAPIResponse(url string) (*http.Response, error) {
-	resp, err := http.Get(url)
+	if Offline {
+		return nil, fmt.Errorf("offline mode, refusing to request %s", url)
+	}
+	resp, err := HTTPGet(url)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request error: %w", err)
 	}
@@ -88,11 +118,34 @@ func GetAPIResponse(url string) (*http.Response, error) {
 		if err := resp.Body.Close(); err != nil {
 			return nil, fmt.Errorf("unexpected HTTP status: %s (error closing body: %w)", resp.Status, err)
 		}
-		return nil, fmt.Errorf("unexpected HTTP status: %s", resp.Status)
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 	return resp, nil
 }
 
+// Concurrency is the maximum number of products fetched in parallel, set from the global --concurrency flag.
+var Concurrency = 8
+
+// RunConcurrently calls fn(i) for every i in [0, n), with at most Concurrency calls running at once,
+// and returns when all calls are done.
+func RunConcurrently(n int, fn func(i int)) {
+	workers := min(max(Concurrency, 1), n)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for i := range jobs {
+				fn(i)
+			}
+		})
+	}
+	for i := range n {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
 func createDirectoryIfNotExists(path string) error {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return os.MkdirAll(path, 0o755)
@@ -102,18 +155,11 @@ func createDirectoryIfNotExists(path string) error {
 
 // RefreshAllCaches runs all cache refresh functions and exits with code 1 if any fail.
 func RefreshAllCaches(cmd *cobra.Command) {
-	if err := FetchAndSaveProducts(cmd); err != nil {
-		os.Exit(1)
-	}
-	if err := FetchAndSaveTags(cmd); err != nil {
-		os.Exit(1)
-	}
-	if err := FetchAndSaveCategories(cmd); err != nil {
+	if Offline {
+		log.Error().Msg("Cannot refresh the cache in offline mode, run the command again without --offline")
 		os.Exit(1)
 	}
-
-	if err := CreateDoNotEditFile(); err != nil {
-		log.Error().Err(err).Msg("Error creating DO_NOT_EDIT_ANYTHING file")
+	if err := refreshCacheLists(cmd); err != nil {
 		os.Exit(1)
 	}
 
@@ -131,6 +177,43 @@ func RefreshAllCaches(cmd *cobra.Command) {
 	}
 }
 
+// refreshCacheLists downloads the products, tags and categories lists. The errors are logged.
+func refreshCacheLists(cmd *cobra.Command) error {
+	if err := FetchAndSaveProducts(cmd); err != nil {
+		return err
+	}
+	if err := FetchAndSaveTags(cmd); err != nil {
+		return err
+	}
+	if err := FetchAndSaveCategories(cmd); err != nil {
+		return err
+	}
+	if err := CreateDoNotEditFile(); err != nil {
+		log.Error().Err(err).Msg("Error creating DO_NOT_EDIT_ANYTHING file")
+		return err
+	}
+	return nil
+}
+
+// RefreshStaleCache refreshes the products, tags and categories lists when the products cache is
+// older than CacheMaxAge, like AnalyzeCacheProductsValidity, but returns the errors instead of
+// exiting, so that the commands that keep running go on with the stale cache.
+func RefreshStaleCache(cmd *cobra.Command) error {
+	productsPath, err := GetProductsPath()
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(productsPath)
+	if err == nil && !info.ModTime().Before(time.Now().Add(-CacheMaxAge)) {
+		return nil
+	}
+	if Offline {
+		return err
+	}
+	log.Warn().Msg("Cache older than " + CacheMaxAge.String() + ". Updating the cache...")
+	return refreshCacheLists(cmd)
+}
+
 // CreateDoNotEditFile creates a DO_NOT_EDIT_ANYTHING file in the geol config directory to warn users not to edit anything there.
 func CreateDoNotEditFile() error {
 	configDir, err := os.UserConfigDir()
@@ -138,7 +221,7 @@ func CreateDoNotEditFile() error {
 		return err
 	}
 	file := filepath.Join(configDir, "geol", "DO_NOT_EDIT_ANYTHING")
-	if err := os.WriteFile(file, []byte("This directory is managed by geol. Do not edit anything here."), 0o644); err != nil {
+	if err := os.WriteFile(file, []byte("This directory is managed by geol. Do not edit anything here, except the optional config.yaml settings file."), 0o644); err != nil {
 		return err
 	}
 	return nil
@@ -146,7 +229,10 @@ func CreateDoNotEditFile() error {
 
 // GetLatestVersionFromGitHub fetches the latest release tag from GitHub
 func GetLatestVersionFromGitHub() string {
-	resp, err := http.Get("https://api.github.com/repos/opt-nc/geol/releases/latest")
+	if Offline {
+		return ""
+	}
+	resp, err := HTTPGet(ReleaseCheckUrl)
 	if err != nil {
 		return ""
 	}